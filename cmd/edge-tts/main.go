@@ -6,14 +6,18 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"regexp"
 	"strings"
 	"syscall"
 	"text/tabwriter"
 
 	"github.com/difyz9/edge-tts-go/internal/constants"
 	"github.com/difyz9/edge-tts-go/pkg/communicate"
+	"github.com/difyz9/edge-tts-go/pkg/endpoint"
 	"github.com/difyz9/edge-tts-go/pkg/submaker"
 	"github.com/difyz9/edge-tts-go/pkg/voices"
 )
@@ -32,6 +36,10 @@ type UtilArgs struct {
 	WriteMedia     string
 	WriteSubtitles string
 	Proxy          string
+	ClientVersion  string
+	AzureRegion    string
+	AzureKey       string
+	Debug          bool
 }
 
 func cleanText(s string) string {
@@ -65,9 +73,16 @@ func main() {
 	// Parse command-line arguments
 	args := parseArgs()
 
+	// Select the service
+	e, err := serviceEndpoint(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
 	// List voices if requested
 	if args.ListVoices {
-		err := printVoices(ctx, args.Proxy)
+		err := printVoices(ctx, args.Proxy, e, newLogger(args))
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error listing voices: %v\n", err)
 			os.Exit(1)
@@ -118,8 +133,11 @@ func main() {
 		os.Exit(1)
 	}
 
-	// Create a SubMaker instance
-	sm := submaker.NewSubMaker()
+	comm.SetEndpoint(e)
+	comm.SetLogger(newLogger(args))
+
+	// Create the subtitle tracks
+	tracks := submaker.NewTracks()
 
 	// Open the output files
 	var audioFile io.WriteCloser
@@ -158,7 +176,7 @@ func main() {
 				os.Exit(1)
 			}
 		} else if chunk.Type == "WordBoundary" || chunk.Type == "SentenceBoundary" {
-			err := sm.Feed(chunk)
+			err := tracks.Feed(chunk)
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "Error feeding %s: %v\n", chunk.Type, err)
 				os.Exit(1)
@@ -172,6 +190,12 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Write word subtitles unless only sentence boundaries were requested
+	sm := tracks.Words
+	if args.Boundary == "SentenceBoundary" {
+		sm = tracks.Sentences
+	}
+
 	// Merge cues if requested
 	if args.WordsInCue > 0 {
 		err := sm.MergeCues(args.WordsInCue)
@@ -183,7 +207,7 @@ func main() {
 
 	// Write subtitles if requested
 	if subFile != nil {
-		_, err := fmt.Fprint(subFile, sm.GetSRT())
+		_, err := fmt.Fprint(subFile, formatSubtitles(sm, args.WriteSubtitles, voiceLocale(args.Voice)))
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error writing subtitles: %v\n", err)
 			os.Exit(1)
@@ -206,21 +230,45 @@ func parseArgs() UtilArgs {
 	flag.StringVar(&args.Rate, "rate", "+0%", "set TTS rate")
 	flag.StringVar(&args.Volume, "volume", "+0%", "set TTS volume")
 	flag.StringVar(&args.Pitch, "pitch", "+0Hz", "set TTS pitch")
-	flag.StringVar(&args.Boundary, "boundary", "WordBoundary", "set boundary type (WordBoundary or SentenceBoundary)")
+	flag.StringVar(&args.Boundary, "boundary", "WordBoundary", "set boundary type (WordBoundary, SentenceBoundary or WordAndSentenceBoundary)")
 	flag.IntVar(&args.WordsInCue, "words-in-cue", 10, "number of words in a subtitle cue")
 	flag.StringVar(&args.WriteMedia, "write-media", "", "send media output to file instead of stdout")
-	flag.StringVar(&args.WriteSubtitles, "write-subtitles", "", "send subtitle output to provided file instead of stderr")
-	flag.StringVar(&args.Proxy, "proxy", "", "use a proxy for TTS and voice list")
+	flag.StringVar(&args.WriteSubtitles, "write-subtitles", "", "send subtitle output to provided file instead of stderr (.srt, .lrc or .ttml)")
+	flag.StringVar(&args.Proxy, "proxy", "", "use a proxy for TTS and voice list (http:// or socks5://, with optional user:password@)")
+	flag.StringVar(&args.ClientVersion, "client-version", constants.ChromiumFullVersion, "Microsoft Edge version sent to the service")
+	flag.StringVar(&args.AzureRegion, "azure-region", "", "use the Azure Speech service in this region instead of Microsoft Edge")
+	flag.StringVar(&args.AzureKey, "azure-key", os.Getenv("AZURE_SPEECH_KEY"), "Azure Speech resource key (default $AZURE_SPEECH_KEY)")
+	flag.BoolVar(&args.Debug, "debug", false, "log the exchanges with the service to stderr")
 
 	flag.Parse()
 
 	return args
 }
 
+// serviceEndpoint returns the endpoint selected by the command-line arguments.
+func serviceEndpoint(args UtilArgs) (endpoint.Endpoint, error) {
+	if args.AzureRegion != "" {
+		e, err := endpoint.Azure(args.AzureRegion, args.AzureKey)
+		if err != nil {
+			return endpoint.Endpoint{}, fmt.Errorf("--azure-region requires --azure-key or $AZURE_SPEECH_KEY: %w", err)
+		}
+		return e, nil
+	}
+	return endpoint.Endpoint{ClientVersion: args.ClientVersion}, nil
+}
+
+// newLogger returns the debug logger selected by the command-line arguments, or nil.
+func newLogger(args UtilArgs) *slog.Logger {
+	if !args.Debug {
+		return nil
+	}
+	return slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+}
+
 // printVoices prints all available voices.
-func printVoices(ctx context.Context, proxy string) error {
+func printVoices(ctx context.Context, proxy string, e endpoint.Endpoint, logger *slog.Logger) error {
 	// Get the list of voices
-	voiceList, err := voices.ListVoices(ctx, proxy)
+	voiceList, err := voices.ListVoicesWithOptions(ctx, voices.Options{Proxy: proxy, Endpoint: e, Logger: logger})
 	if err != nil {
 		return err
 	}
@@ -245,6 +293,31 @@ func printVoices(ctx context.Context, proxy string) error {
 	return w.Flush()
 }
 
+// formatSubtitles formats the subtitles based on the extension of the output file.
+// lang is the language of TTML subtitles.
+func formatSubtitles(sm *submaker.SubMaker, fname, lang string) string {
+	switch strings.ToLower(filepath.Ext(fname)) {
+	case ".lrc":
+		return sm.GetLRC(true)
+	case ".ttml", ".dfxp":
+		return sm.GetTTML(lang)
+	default:
+		return sm.GetSRT()
+	}
+}
+
+// voiceLocaleRe matches the locale of a voice, e.g. "en-US" in "en-US-EmmaNeural",
+// or "zh-CN-liaoning" in "Microsoft Server Speech Text to Speech Voice (zh-CN-liaoning, XiaobeiNeural)".
+var voiceLocaleRe = regexp.MustCompile(`^(?:Microsoft Server Speech Text to Speech Voice \()?([a-z]{2,}-[A-Z]{2,}(?:-[a-z]+)?)[-,]`)
+
+// voiceLocale returns the locale of the voice, or "" if it cannot be found.
+func voiceLocale(voice string) string {
+	if m := voiceLocaleRe.FindStringSubmatch(voice); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
 // isTerminal returns true if the file descriptor is a terminal.
 func isTerminal(fd uintptr) bool {
 	// This is a simplified implementation. In a real implementation,
@@ -3,6 +3,6 @@ module github.com/difyz9/edge-tts-go
 go 1.22.6
 
 require (
-	github.com/google/uuid v1.6.0 // indirect
-	github.com/gorilla/websocket v1.5.3 // indirect
+	github.com/google/uuid v1.6.0
+	github.com/gorilla/websocket v1.5.3
 )
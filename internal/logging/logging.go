@@ -0,0 +1,26 @@
+// Package logging provides helpers for the optional loggers of the package.
+package logging
+
+import (
+	"context"
+	"log/slog"
+)
+
+// discard is a logger that drops every record.
+var discard = slog.New(discardHandler{})
+
+// Or returns logger, or a logger that drops every record if logger is nil.
+func Or(logger *slog.Logger) *slog.Logger {
+	if logger == nil {
+		return discard
+	}
+	return logger
+}
+
+// discardHandler is a slog.Handler that drops every record.
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
@@ -0,0 +1,140 @@
+// Package mp3 contains helpers for the MPEG audio frames produced by the TTS service.
+package mp3
+
+import (
+	"bytes"
+	"time"
+)
+
+// FrameDuration is the duration of a single frame in the service's
+// audio-24khz-48kbitrate-mono-mp3 output format (576 samples at 24 kHz).
+const FrameDuration = 24 * time.Millisecond
+
+// silentFrame is a single MPEG-2 Layer III frame (48 kbit/s, 24 kHz, mono)
+// with zeroed side information and main data, which decodes to silence.
+var silentFrame = func() []byte {
+	frame := make([]byte, 144)
+	copy(frame, []byte{0xFF, 0xF3, 0x64, 0xC4})
+	return frame
+}()
+
+// bitrates holds the Layer III bitrates in kbit/s, indexed by MPEG-1 (0) or MPEG-2/2.5 (1)
+// and the bitrate index from the frame header.
+var bitrates = [2][16]int{
+	{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 0},
+	{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0},
+}
+
+// sampleRates holds the sample rates in Hz, indexed by the version bits and
+// the sample rate index from the frame header.
+var sampleRates = map[byte][3]int{
+	3: {44100, 48000, 32000}, // MPEG-1
+	2: {22050, 24000, 16000}, // MPEG-2
+	0: {11025, 12000, 8000},  // MPEG-2.5
+}
+
+// Duration returns the playback duration of the Layer III frames in data.
+// Bytes that are not part of a valid frame header are skipped.
+func Duration(data []byte) time.Duration {
+	data = skipID3v2(data)
+
+	var total time.Duration
+	for i := 0; i+4 <= len(data); {
+		frameSize, samples, sampleRate, ok := parseHeader(data[i : i+4])
+		if !ok {
+			i++
+			continue
+		}
+		total += time.Duration(samples) * time.Second / time.Duration(sampleRate)
+		i += frameSize
+	}
+	return total
+}
+
+// Counter measures the duration of frames received in pieces, such as the audio
+// messages of the service, which may cut a frame or its header in two. Unlike
+// Duration, a frame is only counted once it is complete. The zero value is ready
+// to use.
+type Counter struct {
+	pending []byte
+}
+
+// Add adds data to the stream and returns the playback duration of the frames it
+// completes. Bytes that are not part of a valid frame header are skipped.
+func (c *Counter) Add(data []byte) time.Duration {
+	c.pending = append(c.pending, data...)
+
+	var total time.Duration
+	i := 0
+	for i+4 <= len(c.pending) {
+		frameSize, samples, sampleRate, ok := parseHeader(c.pending[i : i+4])
+		if !ok {
+			i++
+			continue
+		}
+		if i+frameSize > len(c.pending) {
+			break
+		}
+		total += time.Duration(samples) * time.Second / time.Duration(sampleRate)
+		i += frameSize
+	}
+	c.pending = append(c.pending[:0], c.pending[i:]...)
+	return total
+}
+
+// Silence returns silent frames covering d, rounded to the nearest whole frame.
+func Silence(d time.Duration) []byte {
+	frames := int((d + FrameDuration/2) / FrameDuration)
+	if frames <= 0 {
+		return nil
+	}
+	return bytes.Repeat(silentFrame, frames)
+}
+
+// parseHeader parses a Layer III frame header and returns the frame size in bytes,
+// the number of samples in the frame and the sample rate.
+func parseHeader(h []byte) (frameSize, samples, sampleRate int, ok bool) {
+	if h[0] != 0xFF || h[1]&0xE0 != 0xE0 {
+		return 0, 0, 0, false
+	}
+
+	version := (h[1] >> 3) & 0x03
+	layer := (h[1] >> 1) & 0x03
+	if version == 1 || layer != 1 {
+		return 0, 0, 0, false
+	}
+
+	rates, known := sampleRates[version]
+	sampleRateIndex := (h[2] >> 2) & 0x03
+	if !known || sampleRateIndex == 3 {
+		return 0, 0, 0, false
+	}
+	sampleRate = rates[sampleRateIndex]
+
+	table, coefficient := 0, 144
+	samples = 1152
+	if version != 3 {
+		table, coefficient = 1, 72
+		samples = 576
+	}
+	bitrate := bitrates[table][h[2]>>4]
+	if bitrate == 0 {
+		return 0, 0, 0, false
+	}
+
+	padding := int((h[2] >> 1) & 0x01)
+	frameSize = coefficient*bitrate*1000/sampleRate + padding
+	return frameSize, samples, sampleRate, true
+}
+
+// skipID3v2 strips a leading ID3v2 tag, if present.
+func skipID3v2(data []byte) []byte {
+	if len(data) < 10 || !bytes.HasPrefix(data, []byte("ID3")) {
+		return data
+	}
+	size := int(data[6]&0x7F)<<21 | int(data[7]&0x7F)<<14 | int(data[8]&0x7F)<<7 | int(data[9]&0x7F)
+	if 10+size > len(data) {
+		return nil
+	}
+	return data[10+size:]
+}
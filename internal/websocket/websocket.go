@@ -4,15 +4,24 @@ package websocket
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
 	"encoding/binary"
 	"encoding/json"
+	stderrors "errors"
 	"fmt"
+	"log/slog"
+	"net"
 	"net/http"
 	"net/url"
+	"time"
 
-	"github.com/difyz9/edge-tts-go/internal/constants"
-	"github.com/difyz9/edge-tts-go/internal/drm"
+	"github.com/difyz9/edge-tts-go/internal/logging"
+	"github.com/difyz9/edge-tts-go/internal/proxy"
+	"github.com/difyz9/edge-tts-go/pkg/drm"
+	"github.com/difyz9/edge-tts-go/pkg/endpoint"
 	"github.com/difyz9/edge-tts-go/pkg/errors"
+	"github.com/difyz9/edge-tts-go/pkg/metrics"
+	"github.com/difyz9/edge-tts-go/pkg/record"
 	"github.com/difyz9/edge-tts-go/pkg/types"
 	"github.com/difyz9/edge-tts-go/pkg/util"
 	"github.com/gorilla/websocket"
@@ -21,87 +30,256 @@ import (
 // Client is a WebSocket client for the TTS service.
 type Client struct {
 	conn           *websocket.Conn
+	pending        []types.TTSChunk
+	endpoint       endpoint.Endpoint
 	proxy          string
+	tlsConfig      *tls.Config
 	connectTimeout int
 	receiveTimeout int
+	recorder       *record.Recorder
+	recording      *record.Conn
+	onRetry        func(error)
+	logger         *slog.Logger
+	metrics        metrics.Collector
+	tokens         *drm.TokenGenerator
+	tokenSentAt    float64
+	ctx            context.Context
+	stop           func() bool
 }
 
 // NewClient creates a new WebSocket client.
 func NewClient(proxy string, connectTimeout, receiveTimeout int) *Client {
 	return &Client{
+		endpoint:       endpoint.Default(),
 		proxy:          proxy,
 		connectTimeout: connectTimeout,
 		receiveTimeout: receiveTimeout,
+		logger:         logging.Or(nil),
+		metrics:        metrics.Nop{},
+		tokens:         drm.Default(),
 	}
 }
 
+// SetEndpoint sets the endpoint of the service.
+func (c *Client) SetEndpoint(e endpoint.Endpoint) {
+	c.endpoint = e
+}
+
+// SetTLSConfig sets the TLS configuration of the connection, for example to trust
+// the root CAs of a corporate network. A nil config uses the default configuration.
+func (c *Client) SetTLSConfig(cfg *tls.Config) {
+	c.tlsConfig = cfg
+}
+
+// SetRecorder sets a recorder for the frames exchanged on the next connection.
+func (c *Client) SetRecorder(r *record.Recorder) {
+	c.recorder = r
+}
+
+// SetLogger sets the logger of the client, to which connections, requests and
+// received paths are logged at debug level. A nil logger disables logging.
+func (c *Client) SetLogger(logger *slog.Logger) {
+	c.logger = logging.Or(logger)
+}
+
+// SetMetrics sets the collector of the clock skew corrections. A nil collector
+// disables the metrics.
+func (c *Client) SetMetrics(m metrics.Collector) {
+	c.metrics = metrics.Or(m)
+}
+
+// SetTokenGenerator sets the generator of the Sec-MS-GEC tokens, which holds the
+// clock skew. A nil generator uses the default one, shared by the process.
+func (c *Client) SetTokenGenerator(g *drm.TokenGenerator) {
+	c.tokens = drm.Or(g)
+}
+
+// SetRetryHook sets a function called with the error that caused a connection retry.
+func (c *Client) SetRetryHook(fn func(error)) {
+	c.onRetry = fn
+}
+
 // Connect connects to the TTS service.
+//
+// The connection is retried up to drm.SkewRetries times when the Microsoft Edge
+// endpoint rejects it because of clock skew, or when the service is throttling
+// (429 or 503) and asks, with a Retry-After header, to wait no longer than the
+// connect timeout.
 func (c *Client) Connect(ctx context.Context) error {
-	// Parse the WebSocket URL
-	u, err := url.Parse(constants.WSSURL + "&Sec-MS-GEC=" + drm.GenerateSecMSGEC() +
-		"&Sec-MS-GEC-Version=" + constants.SecMSGECVersion +
-		"&ConnectionId=" + util.ConnectID())
+	// Use the proxy if provided, or else the proxy of the environment
+	proxyFunc, err := proxy.Func(c.proxy)
 	if err != nil {
 		return err
 	}
 
 	// Create dialer with compression enabled (equivalent to compress=15 in Python version)
 	dialer := websocket.Dialer{
-		Proxy:            http.ProxyFromEnvironment,
-		HandshakeTimeout: 0, // No timeout
+		Proxy:             proxyFunc,
+		TLSClientConfig:   c.tlsConfig,
+		HandshakeTimeout:  time.Duration(c.connectTimeout) * time.Second,
 		EnableCompression: true,
 	}
 
-	// Set proxy if provided
-	if c.proxy != "" {
-		proxyURL, err := url.Parse(c.proxy)
-		if err != nil {
-			return err
+	// Connect to the WebSocket server
+	conn, resp, connectionID, err := c.dial(ctx, &dialer)
+	for attempt := 0; err != nil; attempt++ {
+		retry := false
+		if attempt < drm.SkewRetries {
+			var retryErr error
+			retry, retryErr = c.shouldRetry(ctx, connectionID, resp, err)
+			if retryErr != nil {
+				return retryErr
+			}
+		}
+		if !retry {
+			c.logger.Debug("connection failed", "connection_id", connectionID, "error", err)
+			return c.dialError(ctx, resp, err)
 		}
-		dialer.Proxy = http.ProxyURL(proxyURL)
+
+		// Retry the connection
+		conn, resp, connectionID, err = c.dial(ctx, &dialer)
 	}
+	c.logger.Debug("connected", "connection_id", connectionID)
+	c.calibrate(resp)
+	c.logger = c.logger.With("connection_id", connectionID)
+
+	// Enable compression (equivalent to compress=15 in Python version)
+	conn.EnableWriteCompression(true)
 
-	// Set headers
-	header := http.Header{}
-	for k, v := range constants.WSSHeaders {
-		header.Set(k, v)
+	c.conn = conn
+	if c.recorder != nil {
+		c.recording = c.recorder.Conn()
 	}
 
-	// Connect to the WebSocket server
-	conn, resp, err := dialer.DialContext(ctx, u.String(), header)
+	// Close the connection as soon as the context is done, which interrupts
+	// any pending read
+	c.ctx = ctx
+	c.stop = context.AfterFunc(ctx, func() {
+		conn.Close()
+	})
+	return nil
+}
+
+// dial dials the service with a new connection ID, which it returns.
+func (c *Client) dial(ctx context.Context, dialer *websocket.Dialer) (*websocket.Conn, *http.Response, string, error) {
+	// Parse the WebSocket URL, keeping the time of the token in case it is rejected
+	connectionID := util.ConnectID()
+	token, sentAt := c.tokens.GenerateSecMSGECWithTimestamp(c.endpoint.Token())
+	c.tokenSentAt = sentAt
+	u, err := url.Parse(c.endpoint.SynthesisURL(token, connectionID))
 	if err != nil {
-		if resp != nil && resp.StatusCode == http.StatusForbidden {
-			err = drm.HandleClientResponseError(resp)
-			if err != nil {
-				return err
-			}
+		return nil, nil, connectionID, err
+	}
 
-			// Retry the connection
-			u, err = url.Parse(constants.WSSURL + "&Sec-MS-GEC=" + drm.GenerateSecMSGEC() +
-				"&Sec-MS-GEC-Version=" + constants.SecMSGECVersion +
-				"&ConnectionId=" + util.ConnectID())
-			if err != nil {
-				return err
-			}
+	// gorilla/websocket only bounds the handshake with the handshake timeout, so close
+	// the connection when ctx is done to interrupt a handshake the service does not answer
+	var stops []func() bool
+	d := *dialer
+	d.NetDialContext = func(dialCtx context.Context, network, addr string) (net.Conn, error) {
+		netConn, err := (&net.Dialer{}).DialContext(dialCtx, network, addr)
+		if err == nil {
+			stops = append(stops, context.AfterFunc(ctx, func() { netConn.Close() }))
+		}
+		return netConn, err
+	}
+
+	c.logger.Debug("connecting", "connection_id", connectionID, "host", u.Host)
+	conn, resp, err := d.DialContext(ctx, u.String(), c.endpoint.WSSHeaders(connectionID))
+	for _, stop := range stops {
+		stop()
+	}
+	return conn, resp, connectionID, err
+}
 
-			conn, _, err = dialer.DialContext(ctx, u.String(), header)
-			if err != nil {
-				return err
+// calibrate adjusts the clock skew to the date of a handshake response, before
+// the service starts rejecting tokens.
+func (c *Client) calibrate(resp *http.Response) {
+	if resp == nil || !c.endpoint.UsesDRM() {
+		return
+	}
+	if adjustment, ok := c.tokens.Calibrate(resp.Header); ok {
+		c.logger.Debug("clock skew calibrated", "adjustment_seconds", adjustment)
+		c.metrics.ObserveSkewCorrection(adjustment)
+	}
+}
+
+// shouldRetry reports whether a failed handshake should be retried, after adjusting
+// the clock skew or waiting for the delay requested by the service.
+func (c *Client) shouldRetry(ctx context.Context, connectionID string, resp *http.Response, err error) (bool, error) {
+	if resp == nil {
+		return false, nil
+	}
+	rejected := &errors.HandshakeError{StatusCode: resp.StatusCode, Header: resp.Header, Err: err}
+
+	switch {
+	case resp.StatusCode == http.StatusForbidden && c.endpoint.UsesDRM():
+		// Only the Microsoft Edge endpoint rejects requests because of clock skew
+		adjustment, err := c.tokens.HandleClientResponseError(resp, c.tokenSentAt, c.logger)
+		if err != nil {
+			var tokenErr *errors.TokenRejectedError
+			if stderrors.As(err, &tokenErr) {
+				tokenErr.Version = c.endpoint.SecMSGECVersion()
 			}
-		} else {
-			return err
+			return false, err
 		}
+		c.metrics.ObserveSkewCorrection(adjustment)
+
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable:
+		wait, ok := rejected.RetryAfter()
+		if !ok || wait > time.Duration(c.connectTimeout)*time.Second {
+			return false, nil
+		}
+		c.logger.Debug("throttled", "connection_id", connectionID, "status", resp.Status, "retry_after", wait)
+
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return false, ctx.Err()
+		}
+
+	default:
+		return false, nil
 	}
 
-	// Enable compression (equivalent to compress=15 in Python version)
-	conn.EnableWriteCompression(true)
+	c.logger.Debug("retrying connection", "connection_id", connectionID, "status", resp.Status)
+	if c.onRetry != nil {
+		c.onRetry(rejected)
+	}
+	return true, nil
+}
 
-	c.conn = conn
-	return nil
+// dialError converts an error returned while dialing the service, along with the
+// handshake response if one was received.
+func (c *Client) dialError(ctx context.Context, resp *http.Response, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if stderrors.Is(err, context.DeadlineExceeded) || isTimeout(err) {
+		return &errors.TimeoutError{
+			Op:    "handshake",
+			Limit: time.Duration(c.connectTimeout) * time.Second,
+			Msg:   fmt.Sprintf("handshake did not complete within %ds", c.connectTimeout),
+		}
+	}
+	if resp != nil {
+		return &errors.HandshakeError{StatusCode: resp.StatusCode, Header: resp.Header, Err: err}
+	}
+	return &errors.HandshakeError{Err: err}
+}
+
+// isTimeout reports whether err is a network timeout.
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return stderrors.As(err, &netErr) && netErr.Timeout()
 }
 
 // Close closes the WebSocket connection.
 func (c *Client) Close() error {
+	if c.stop != nil {
+		c.stop()
+	}
 	if c.conn != nil {
 		return c.conn.Close()
 	}
@@ -114,12 +292,24 @@ func (c *Client) SendCommandRequest(ttsConfig types.TTSConfig) error {
 		return fmt.Errorf("not connected")
 	}
 
-	wordBoundary := ttsConfig.Boundary == "WordBoundary" || ttsConfig.Boundary == ""
 	wd := "true"
 	sq := "false"
-	if !wordBoundary {
+	switch ttsConfig.Boundary {
+	case "SentenceBoundary":
 		wd = "false"
 		sq = "true"
+	case "WordAndSentenceBoundary":
+		sq = "true"
+	}
+
+	// Only mention the optional events when enabled, to keep the request
+	// identical to the one sent by Microsoft Edge otherwise.
+	extra := ""
+	if ttsConfig.Bookmarks {
+		extra += `"bookmarkEnabled":"true",`
+	}
+	if ttsConfig.Visemes {
+		extra += `"visemeEnabled":"true",`
 	}
 
 	message := fmt.Sprintf(
@@ -127,12 +317,21 @@ func (c *Client) SendCommandRequest(ttsConfig types.TTSConfig) error {
 			"Content-Type:application/json; charset=utf-8\r\n"+
 			"Path:speech.config\r\n\r\n"+
 			`{"context":{"synthesis":{"audio":{"metadataoptions":{`+
-			`"sentenceBoundaryEnabled":"%s","wordBoundaryEnabled":"%s"},`+
+			`%s"sentenceBoundaryEnabled":"%s","wordBoundaryEnabled":"%s"},`+
 			`"outputFormat":"audio-24khz-48kbitrate-mono-mp3"`+
 			`}}}}`,
-		util.DateToString(), sq, wd)
+		util.DateToString(), extra, sq, wd)
+
+	c.logger.Debug("sending speech.config", "boundary", ttsConfig.Boundary)
+	return c.writeMessage(websocket.TextMessage, []byte(message))
+}
 
-	return c.conn.WriteMessage(websocket.TextMessage, []byte(message))
+// writeMessage writes a message to the connection and records it.
+func (c *Client) writeMessage(messageType int, data []byte) error {
+	if c.recording != nil {
+		c.recording.Sent(messageType, data)
+	}
+	return c.conn.WriteMessage(messageType, data)
 }
 
 // SendSSMLRequest sends the SSML request to the service.
@@ -141,24 +340,66 @@ func (c *Client) SendSSMLRequest(partialText []byte, ttsConfig types.TTSConfig)
 		return fmt.Errorf("not connected")
 	}
 
+	requestID := util.ConnectID()
 	message := util.SSMLHeadersPlusData(
-		util.ConnectID(),
+		requestID,
 		util.DateToString(),
 		util.MkSSML(ttsConfig, string(partialText)),
 	)
+	c.logger.Debug("sending ssml request", "request_id", requestID, "voice", ttsConfig.Voice, "bytes", len(partialText))
 
-	return c.conn.WriteMessage(websocket.TextMessage, []byte(message))
+	return c.writeMessage(websocket.TextMessage, []byte(message))
 }
 
-// ReceiveMessage receives a message from the service.
+// ReceiveMessage receives a message from the service. A single audio.metadata
+// message may hold several entries, which are returned by subsequent calls.
 func (c *Client) ReceiveMessage() (types.TTSChunk, error) {
 	if c.conn == nil {
 		return types.TTSChunk{}, fmt.Errorf("not connected")
 	}
 
+	for len(c.pending) == 0 {
+		chunks, err := c.receiveFrame()
+		if err != nil {
+			return types.TTSChunk{}, err
+		}
+		c.pending = chunks
+	}
+
+	chunk := c.pending[0]
+	c.pending = c.pending[1:]
+	return chunk, nil
+}
+
+// receiveFrame receives a single frame from the service and returns the chunks it holds.
+func (c *Client) receiveFrame() ([]types.TTSChunk, error) {
+	if c.receiveTimeout > 0 {
+		err := c.conn.SetReadDeadline(time.Now().Add(time.Duration(c.receiveTimeout) * time.Second))
+		if err != nil {
+			return nil, errors.NewWebSocketError(err.Error())
+		}
+	}
+
 	messageType, data, err := c.conn.ReadMessage()
 	if err != nil {
-		return types.TTSChunk{}, errors.NewWebSocketError(err.Error())
+		if c.ctx != nil && c.ctx.Err() != nil {
+			return nil, c.ctx.Err()
+		}
+		var closeErr *websocket.CloseError
+		if stderrors.As(err, &closeErr) {
+			return nil, &errors.CloseError{Code: closeErr.Code, Reason: closeErr.Text}
+		}
+		if isTimeout(err) {
+			return nil, &errors.TimeoutError{
+				Op:    "receive",
+				Limit: time.Duration(c.receiveTimeout) * time.Second,
+				Msg:   fmt.Sprintf("no message received within %ds", c.receiveTimeout),
+			}
+		}
+		return nil, errors.NewWebSocketError(err.Error())
+	}
+	if c.recording != nil {
+		c.recording.Received(messageType, data)
 	}
 
 	switch messageType {
@@ -166,29 +407,30 @@ func (c *Client) ReceiveMessage() (types.TTSChunk, error) {
 		headers, messageData := util.ProcessWebsocketMessage(data)
 
 		path := headers["Path"]
+		c.logger.Debug("received message", "path", path, "request_id", headers["X-RequestId"])
 		if path == "audio.metadata" {
 			// Parse the metadata and return it
 			return c.parseMetadata(messageData)
 		} else if path == "turn.end" {
 			// Return a special chunk to indicate the end of the turn
-			return types.TTSChunk{Type: "turn.end"}, nil
+			return []types.TTSChunk{{Type: "turn.end"}}, nil
 		} else if path != "response" && path != "turn.start" {
-			return types.TTSChunk{}, errors.NewUnknownResponseError("unknown path received: " + path)
+			return nil, &errors.ProtocolError{Err: errors.ErrUnknownResponse, Path: path, Msg: "unknown path received: " + path}
 		}
 
 		// For response and turn.start, just return an empty chunk
-		return types.TTSChunk{Type: path}, nil
+		return []types.TTSChunk{{Type: path}}, nil
 
 	case websocket.BinaryMessage:
 		// Message is too short to contain header length
 		if len(data) < 2 {
-			return types.TTSChunk{}, errors.NewUnexpectedResponseError("binary message is too short")
+			return nil, errors.NewUnexpectedResponseError("binary message is too short")
 		}
 
 		// The first two bytes of the binary message contain the header length
 		headerLength := int(binary.BigEndian.Uint16(data[:2]))
 		if headerLength+2 > len(data) {
-			return types.TTSChunk{}, errors.NewUnexpectedResponseError("header length is greater than the length of the data")
+			return nil, errors.NewUnexpectedResponseError("header length is greater than the length of the data")
 		}
 
 		// Extract the audio data directly
@@ -212,7 +454,11 @@ func (c *Client) ReceiveMessage() (types.TTSChunk, error) {
 		// Check if the path is audio
 		pathHeader, exists := headers["Path"]
 		if !exists || pathHeader != "audio" {
-			return types.TTSChunk{}, errors.NewUnexpectedResponseError("received binary message, but the path is not audio")
+			return nil, &errors.ProtocolError{
+				Err:  errors.ErrUnexpectedResponse,
+				Path: pathHeader,
+				Msg:  "received binary message, but the path is not audio",
+			}
 		}
 		
 		// Check content type
@@ -220,94 +466,93 @@ func (c *Client) ReceiveMessage() (types.TTSChunk, error) {
 		if !hasContentType {
 			// No Content-Type header
 			if len(audioBinaryData) == 0 {
-				return types.TTSChunk{Type: "audio", Data: []byte{}}, nil
+				return []types.TTSChunk{{Type: "audio", Data: []byte{}}}, nil
 			}
 			// If the data is not empty, then we need to raise an exception
-			return types.TTSChunk{}, errors.NewUnexpectedResponseError("received binary message with no Content-Type, but with data")
+			return nil, errors.NewUnexpectedResponseError("received binary message with no Content-Type, but with data")
 		}
 		
 		// Has Content-Type header
 		if contentType != "audio/mpeg" {
-			return types.TTSChunk{}, errors.NewUnexpectedResponseError("received binary message, but with an unexpected Content-Type: " + contentType)
+			return nil, errors.NewUnexpectedResponseError("received binary message, but with an unexpected Content-Type: " + contentType)
 		}
 		
 		// If the data is empty now, then we need to raise an exception
 		if len(audioBinaryData) == 0 {
-			return types.TTSChunk{}, errors.NewUnexpectedResponseError("received binary message, but it is missing the audio data")
+			return nil, errors.NewUnexpectedResponseError("received binary message, but it is missing the audio data")
 		}
 		
 		// Return the audio data
-		return types.TTSChunk{Type: "audio", Data: audioBinaryData}, nil
+		return []types.TTSChunk{{Type: "audio", Data: audioBinaryData}}, nil
 
 	default:
-		return types.TTSChunk{}, errors.NewUnexpectedResponseError("unexpected message type")
+		return nil, errors.NewUnexpectedResponseError("unexpected message type")
 	}
 }
 
-// parseMetadata parses the metadata from the message data.
-func (c *Client) parseMetadata(data []byte) (types.TTSChunk, error) {
-	var jsonData map[string]interface{}
-	err := json.Unmarshal(data, &jsonData)
-	if err != nil {
-		return types.TTSChunk{}, err
-	}
+// metadataMessage is the body of an audio.metadata message.
+type metadataMessage struct {
+	Metadata []metadataEntry `json:"Metadata"`
+}
 
-	metadata, ok := jsonData["Metadata"].([]interface{})
-	if !ok {
-		return types.TTSChunk{}, errors.NewUnexpectedResponseError("invalid metadata format")
-	}
+// metadataEntry is a single entry of an audio.metadata message.
+type metadataEntry struct {
+	Type string `json:"Type"`
+	Data struct {
+		Offset   float64 `json:"Offset"`
+		Duration float64 `json:"Duration"`
+		Text     struct {
+			Text string `json:"Text"`
+		} `json:"text"`
+		Bookmark string `json:"Bookmark"`
+		VisemeID int    `json:"VisemeId"`
+	} `json:"Data"`
+}
 
-	for _, metaObj := range metadata {
-		metaMap, ok := metaObj.(map[string]interface{})
-		if !ok {
-			continue
+// parseMetadata parses every entry of the metadata from the message data.
+func (c *Client) parseMetadata(data []byte) ([]types.TTSChunk, error) {
+	var message metadataMessage
+	err := json.Unmarshal(data, &message)
+	if err != nil {
+		return nil, &errors.ProtocolError{
+			Err:  errors.ErrUnexpectedResponse,
+			Path: "audio.metadata",
+			Msg:  "invalid metadata format: " + err.Error(),
 		}
+	}
 
-		metaType, ok := metaMap["Type"].(string)
-		if !ok {
+	chunks := make([]types.TTSChunk, 0, len(message.Metadata))
+	for _, entry := range message.Metadata {
+		switch entry.Type {
+		case "WordBoundary", "SentenceBoundary":
+			chunks = append(chunks, types.TTSChunk{
+				Type:     entry.Type,
+				Offset:   entry.Data.Offset,
+				Duration: entry.Data.Duration,
+				Text:     entry.Data.Text.Text,
+			})
+		case "Bookmark":
+			chunks = append(chunks, types.TTSChunk{
+				Type:   entry.Type,
+				Offset: entry.Data.Offset,
+				Text:   entry.Data.Bookmark,
+			})
+		case "Viseme":
+			chunks = append(chunks, types.TTSChunk{
+				Type:     entry.Type,
+				Offset:   entry.Data.Offset,
+				VisemeID: entry.Data.VisemeID,
+			})
+		case "SessionEnd":
 			continue
-		}
-
-		if metaType == "WordBoundary" || metaType == "SentenceBoundary" {
-			metaData, ok := metaMap["Data"].(map[string]interface{})
-			if !ok {
-				continue
+		default:
+			return nil, &errors.ProtocolError{
+				Err:  errors.ErrUnknownResponse,
+				Path: "audio.metadata",
+				Msg:  "unknown metadata type: " + entry.Type,
 			}
-
-			offset, ok := metaData["Offset"].(float64)
-			if !ok {
-				continue
-			}
-
-			duration, ok := metaData["Duration"].(float64)
-			if !ok {
-				continue
-			}
-
-			textData, ok := metaData["text"].(map[string]interface{})
-			if !ok {
-				continue
-			}
-
-			text, ok := textData["Text"].(string)
-			if !ok {
-				continue
-			}
-
-			return types.TTSChunk{
-				Type:     metaType,
-				Offset:   offset,
-				Duration: duration,
-				Text:     text,
-			}, nil
-		}
-
-		if metaType == "SessionEnd" {
-			continue
 		}
-
-		return types.TTSChunk{}, errors.NewUnknownResponseError("unknown metadata type: " + metaType)
 	}
 
-	return types.TTSChunk{}, errors.NewUnexpectedResponseError("no WordBoundary or SentenceBoundary metadata found")
+	return chunks, nil
 }
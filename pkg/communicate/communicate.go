@@ -3,23 +3,43 @@ package communicate
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
 	"sync"
+	"time"
 
 	"github.com/difyz9/edge-tts-go/internal/constants"
-	"github.com/difyz9/edge-tts-go/internal/websocket"
+	"github.com/difyz9/edge-tts-go/internal/logging"
+	"github.com/difyz9/edge-tts-go/internal/mp3"
+	"github.com/difyz9/edge-tts-go/pkg/drm"
+	"github.com/difyz9/edge-tts-go/pkg/endpoint"
 	"github.com/difyz9/edge-tts-go/pkg/errors"
+	"github.com/difyz9/edge-tts-go/pkg/metrics"
+	"github.com/difyz9/edge-tts-go/pkg/ratelimit"
+	"github.com/difyz9/edge-tts-go/pkg/record"
 	"github.com/difyz9/edge-tts-go/pkg/types"
 	"github.com/difyz9/edge-tts-go/pkg/util"
 )
 
 // Communicate is the main struct for communicating with the TTS service.
 type Communicate struct {
+	text           string
 	texts          [][]byte
+	textsErr       error
 	ttsConfig      types.TTSConfig
 	proxy          string
+	tlsConfig      *tls.Config
+	endpoint       endpoint.Endpoint
+	recorder       *record.Recorder
+	newTransport   TransportFactory
+	hooks          Hooks
+	logger         *slog.Logger
+	metrics        metrics.Collector
+	limiter        *ratelimit.Limiter
+	tokens         *drm.TokenGenerator
 	connectTimeout int
 	receiveTimeout int
 	state          types.CommunicateState
@@ -77,18 +97,22 @@ func NewCommunicate(
 		return nil, err
 	}
 
-	// Clean and escape the text
+	// Clean the text, and escape and split it into multiple strings
 	cleanText := util.RemoveIncompatibleCharacters(text)
-	escapedText := util.EscapeXML(cleanText)
-
-	// Split the text into multiple strings
-	texts := util.SplitTextByByteLength(escapedText, util.CalcMaxMesgSize(ttsConfig))
+	texts, err := splitText(cleanText, ttsConfig)
+	if err != nil {
+		return nil, err
+	}
 
 	// Create the Communicate instance
 	return &Communicate{
+		text:           cleanText,
 		texts:          texts,
 		ttsConfig:      ttsConfig,
 		proxy:          proxy,
+		endpoint:       endpoint.Default(),
+		logger:         logging.Or(nil),
+		metrics:        metrics.Nop{},
 		connectTimeout: connectTimeout,
 		receiveTimeout: receiveTimeout,
 		state: types.CommunicateState{
@@ -100,7 +124,100 @@ func NewCommunicate(
 	}, nil
 }
 
+// splitText escapes the text and splits it into the partial texts sent to the service.
+// <bookmark mark="..."/> elements in the text are only sent as SSML when bookmarks
+// are enabled, and are otherwise spoken as is.
+func splitText(text string, ttsConfig types.TTSConfig) ([][]byte, error) {
+	escapedText := util.EscapeXML(text)
+	maxSize := util.CalcMaxMesgSize(ttsConfig)
+	if ttsConfig.Bookmarks {
+		escapedText = util.RestoreBookmarks(escapedText)
+		if err := util.ValidateBookmarks(escapedText, maxSize); err != nil {
+			return nil, err
+		}
+	}
+	return util.SplitTextByByteLength(escapedText, maxSize), nil
+}
+
+// SetBookmarks enables Bookmark chunks for <bookmark mark="..."/> elements in the text.
+// Without it, such elements are spoken as literal text. A bookmark too long to fit in
+// a request makes Stream fail. It must be called before Stream.
+func (c *Communicate) SetBookmarks(enabled bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ttsConfig.Bookmarks = enabled
+	c.texts, c.textsErr = splitText(c.text, c.ttsConfig)
+}
+
+// SetVisemes enables Viseme chunks. It must be called before Stream.
+func (c *Communicate) SetVisemes(enabled bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ttsConfig.Visemes = enabled
+}
+
+// SetEndpoint sets the endpoint of the service, for example to update the client
+// version or to use a ttstest.Server. It must be called before Stream.
+func (c *Communicate) SetEndpoint(e endpoint.Endpoint) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.endpoint = e
+}
+
+// SetTLSConfig sets the TLS configuration of the connections, for example to trust
+// the root CAs of a corporate network. It must be called before Stream.
+func (c *Communicate) SetTLSConfig(cfg *tls.Config) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tlsConfig = cfg
+}
+
+// SetRecorder records every frame exchanged with the service. It must be called before Stream.
+func (c *Communicate) SetRecorder(r *record.Recorder) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.recorder = r
+}
+
+// SetLogger sets the logger to which the synthesis is logged at debug level, with
+// the voice, chunk index, connection and request IDs. A nil logger disables logging.
+// It must be called before Stream.
+func (c *Communicate) SetLogger(logger *slog.Logger) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.logger = logging.Or(logger)
+}
+
+// SetMetrics sets the collector of the synthesis metrics. A nil collector disables
+// the metrics. It must be called before Stream.
+func (c *Communicate) SetMetrics(m metrics.Collector) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.metrics = metrics.Or(m)
+}
+
+// SetLimiter sets the limiter of the connections to the service, which is usually
+// shared with other Communicate instances. It must be called before Stream.
+func (c *Communicate) SetLimiter(l *ratelimit.Limiter) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.limiter = l
+}
+
+// SetTokenGenerator sets the generator of the Sec-MS-GEC tokens, which holds the
+// clock skew. By default, the generator shared by the process is used. It must be
+// called before Stream.
+func (c *Communicate) SetTokenGenerator(g *drm.TokenGenerator) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tokens = g
+}
+
 // Stream streams audio and metadata from the service.
+//
+// The chunk channel is closed when the stream ends, after which the error channel
+// yields the error that ended it, if any. A consumer that stops reading early must
+// cancel ctx: the connection is then closed and ctx.Err() is reported.
 func (c *Communicate) Stream(ctx context.Context) (<-chan types.TTSChunk, <-chan error) {
 	chunkChan := make(chan types.TTSChunk)
 	errChan := make(chan error, 1)
@@ -114,46 +231,87 @@ func (c *Communicate) Stream(ctx context.Context) (<-chan types.TTSChunk, <-chan
 		return chunkChan, errChan
 	}
 	c.state.StreamWasCalled = true
+	textsErr := c.textsErr
 	c.mu.Unlock()
 
+	// Check if the text could be split into requests
+	if textsErr != nil {
+		errChan <- textsErr
+		close(chunkChan)
+		return chunkChan, errChan
+	}
+
 	go func() {
 		defer close(chunkChan)
 		defer close(errChan)
 
 		// Stream the audio and metadata from the service
-		for _, partialText := range c.texts {
+		for i, partialText := range c.texts {
+			if err := ctx.Err(); err != nil {
+				c.metrics.ObserveRequest(c.ttsConfig.Voice, metrics.ErrorCategory(err))
+				errChan <- err
+				return
+			}
+
 			c.mu.Lock()
 			c.state.PartialText = partialText
 			c.mu.Unlock()
 
-			err := c.streamPartialText(ctx, chunkChan)
+			err := c.streamPartialText(ctx, i, chunkChan)
 			if err != nil {
+				// The caller canceled the stream: the error of the interrupted send or
+				// receive is a consequence of it, so return the context error as is
+				if ctxErr := ctx.Err(); ctxErr != nil {
+					err = ctxErr
+				} else {
+					err = &errors.SynthesisError{Chunk: i, Voice: c.ttsConfig.Voice, Err: err}
+				}
+				c.logger.Debug("synthesis failed", "voice", c.ttsConfig.Voice, "chunk", i, "error", err)
+				c.limiter.Report(err)
+				c.metrics.IncError(metrics.ErrorCategory(err))
+				c.metrics.ObserveRequest(c.ttsConfig.Voice, metrics.ErrorCategory(err))
 				errChan <- err
 				return
 			}
 		}
+
+		c.metrics.ObserveRequest(c.ttsConfig.Voice, metrics.OutcomeOK)
 	}()
 
 	return chunkChan, errChan
 }
 
-// streamPartialText streams a partial text to the service.
-func (c *Communicate) streamPartialText(ctx context.Context, chunkChan chan<- types.TTSChunk) error {
-	// Create a new WebSocket client
-	client := websocket.NewClient(c.proxy, c.connectTimeout, c.receiveTimeout)
+// streamPartialText streams the partial text at the given index to the service.
+func (c *Communicate) streamPartialText(ctx context.Context, index int, chunkChan chan<- types.TTSChunk) error {
+	// Wait for the limiter to allow the request
+	c.mu.Lock()
+	characters := util.UnescapedLength(c.state.PartialText)
+	c.mu.Unlock()
+	release, err := c.limiter.Acquire(ctx, characters)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	// Create a new transport, by default a WebSocket client
+	client := c.transport(ctx, index)
+	started := time.Now()
 
 	// Connect to the service
-	err := client.Connect(ctx)
+	err = client.Connect(ctx)
 	if err != nil {
 		return err
 	}
 	defer client.Close()
+	c.metrics.ObserveHandshake(time.Since(started))
+	c.hooks.connect(index, time.Since(started))
 
 	// Send the command request
 	err = client.SendCommandRequest(c.ttsConfig)
 	if err != nil {
 		return err
 	}
+	c.hooks.configSent(index)
 
 	// Send the SSML request
 	c.mu.Lock()
@@ -162,26 +320,61 @@ func (c *Communicate) streamPartialText(ctx context.Context, chunkChan chan<- ty
 	if err != nil {
 		return err
 	}
+	sent := time.Now()
 
-	// Receive messages from the service
+	// Receive messages from the service. Audio messages may cut MP3 frames, so
+	// their duration is measured over the whole turn.
+	var audio mp3.Counter
 	audioWasReceived := false
+	turnEnd := 0.0
 	for {
 		chunk, err := client.ReceiveMessage()
 		if err != nil {
 			return err
 		}
 
+		// Offsets restart at zero for every SSML request, so shift them
+		// to be relative to the start of the whole audio
+		if chunk.Type != "audio" {
+			c.mu.Lock()
+			chunk.Offset += c.state.OffsetCompensation
+			c.mu.Unlock()
+		}
+
 		if chunk.Type == "audio" {
+			if !audioWasReceived {
+				c.metrics.ObserveFirstAudio(time.Since(sent))
+				c.hooks.firstAudio(index, time.Since(sent))
+			}
 			audioWasReceived = true
-			chunkChan <- chunk
+			c.metrics.AddAudio(len(chunk.Data), audio.Add(chunk.Data))
+			if err := send(ctx, chunkChan, chunk); err != nil {
+				return err
+			}
+		} else if chunk.Type == "Bookmark" || chunk.Type == "Viseme" {
+			if err := send(ctx, chunkChan, chunk); err != nil {
+				return err
+			}
 		} else if chunk.Type == "WordBoundary" || chunk.Type == "SentenceBoundary" {
-			chunkChan <- chunk
+			c.hooks.boundary(index, chunk)
+			if err := send(ctx, chunkChan, chunk); err != nil {
+				return err
+			}
 
-			// Update the last duration offset for use by the next SSML request
-			c.mu.Lock()
-			c.state.LastDurationOffset = chunk.Offset + chunk.Duration
-			c.mu.Unlock()
+			// Update the last duration offset for use by the next SSML request.
+			// With both boundary types enabled, a word may end before the
+			// sentence it belongs to, so keep the furthest end of this turn.
+			if end := chunk.Offset + chunk.Duration; end > turnEnd {
+				turnEnd = end
+				c.mu.Lock()
+				c.state.LastDurationOffset = end
+				c.mu.Unlock()
+			}
+		} else if chunk.Type == "turn.start" {
+			c.hooks.turnStart(index)
 		} else if chunk.Type == "turn.end" {
+			c.hooks.turnEnd(index)
+
 			// Update the offset compensation for the next SSML request
 			c.mu.Lock()
 			c.state.OffsetCompensation = c.state.LastDurationOffset
@@ -203,9 +396,21 @@ func (c *Communicate) streamPartialText(ctx context.Context, chunkChan chan<- ty
 		return errors.NewNoAudioReceivedError("no audio was received. Please verify that your parameters are correct.")
 	}
 
+	c.logger.Debug("chunk complete", "voice", c.ttsConfig.Voice, "chunk", index, "elapsed", time.Since(started))
+	c.hooks.chunkComplete(index, time.Since(started))
 	return nil
 }
 
+// send sends a chunk to the consumer, unless ctx is done first.
+func send(ctx context.Context, chunkChan chan<- types.TTSChunk, chunk types.TTSChunk) error {
+	select {
+	case chunkChan <- chunk:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // Save saves the audio and metadata to the specified files.
 func (c *Communicate) Save(ctx context.Context, audioFname string, metadataFname string) error {
 	// Open the audio file
@@ -225,7 +430,9 @@ func (c *Communicate) Save(ctx context.Context, audioFname string, metadataFname
 		defer metadataFile.Close()
 	}
 
-	// Stream the audio and metadata
+	// Stream the audio and metadata, stopping the stream if we return early
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 	chunkChan, errChan := c.Stream(ctx)
 
 	// Process the chunks
@@ -255,7 +462,9 @@ func (c *Communicate) Save(ctx context.Context, audioFname string, metadataFname
 
 // StreamToWriter streams the audio to the specified writer.
 func (c *Communicate) StreamToWriter(ctx context.Context, w io.Writer) error {
-	// Stream the audio and metadata
+	// Stream the audio and metadata, stopping the stream if we return early
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 	chunkChan, errChan := c.Stream(ctx)
 
 	// Process the chunks
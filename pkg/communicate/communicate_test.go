@@ -0,0 +1,578 @@
+package communicate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/difyz9/edge-tts-go/internal/mp3"
+	"github.com/difyz9/edge-tts-go/pkg/drm"
+	"github.com/difyz9/edge-tts-go/pkg/endpoint"
+	edgeerrors "github.com/difyz9/edge-tts-go/pkg/errors"
+	"github.com/difyz9/edge-tts-go/pkg/metrics"
+	"github.com/difyz9/edge-tts-go/pkg/ttstest"
+	"github.com/difyz9/edge-tts-go/pkg/types"
+)
+
+// newTestCommunicate creates a Communicate for text connected to server, with its
+// own token generator so that tests do not share a clock skew.
+func newTestCommunicate(t *testing.T, server *ttstest.Server, text string) *Communicate {
+	t.Helper()
+	comm, err := NewCommunicate(text, "", "", "", "", "", 5, 1)
+	if err != nil {
+		t.Fatalf("NewCommunicate: %v", err)
+	}
+	comm.SetEndpoint(server.Endpoint())
+	comm.SetTokenGenerator(drm.NewTokenGenerator(nil))
+	return comm
+}
+
+// waitForGoroutines waits until at most n goroutines are running, and reports
+// whether they are.
+func waitForGoroutines(n int) bool {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if runtime.NumGoroutine() <= n {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return runtime.NumGoroutine() <= n
+}
+
+func TestStreamCancelDoesNotLeak(t *testing.T) {
+	server := ttstest.NewServer()
+	defer server.Close()
+
+	before := runtime.NumGoroutine()
+
+	comm := newTestCommunicate(t, server, "one two three four five six seven eight")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	chunkChan, errChan := comm.Stream(ctx)
+
+	// Stop reading after the first chunk
+	if _, ok := <-chunkChan; !ok {
+		t.Fatal("stream ended before the first chunk")
+	}
+	cancel()
+
+	select {
+	case err := <-errChan:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("error = %v, want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no error after cancel")
+	}
+
+	// The producer closes the chunk channel when it returns
+	select {
+	case _, ok := <-chunkChan:
+		if ok {
+			t.Fatal("chunk received after cancel")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("chunk channel not closed after cancel")
+	}
+
+	// The producer, the client connection and the server handler must all exit
+	if !waitForGoroutines(before) {
+		t.Fatalf("goroutines = %d after cancel, want at most %d", runtime.NumGoroutine(), before)
+	}
+}
+
+// cancelingTransport wraps a Transport and cancels the stream when sending the
+// SSML request, failing like a write interrupted by the cancellation.
+type cancelingTransport struct {
+	Transport
+	cancel context.CancelFunc
+}
+
+func (t *cancelingTransport) SendSSMLRequest([]byte, types.TTSConfig) error {
+	t.cancel()
+	return edgeerrors.NewWebSocketError("write: use of closed network connection")
+}
+
+func TestStreamCancelInRequest(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(t *testing.T, comm *Communicate, cancel context.CancelFunc)
+	}{
+		{
+			name: "handshake",
+			setup: func(t *testing.T, comm *Communicate, cancel context.CancelFunc) {
+				// A service that cancels the stream and never answers the handshake
+				release := make(chan struct{})
+				server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					cancel()
+					select {
+					case <-release:
+					case <-r.Context().Done():
+					}
+				}))
+				t.Cleanup(server.Close)
+				t.Cleanup(func() { close(release) })
+				comm.SetEndpoint(endpoint.Endpoint{WSSURL: "ws" + strings.TrimPrefix(server.URL, "http") + "/"})
+			},
+		},
+		{
+			name: "SSML request",
+			setup: func(t *testing.T, comm *Communicate, cancel context.CancelFunc) {
+				server := ttstest.NewServer()
+				t.Cleanup(server.Close)
+				comm.SetEndpoint(server.Endpoint())
+				comm.SetTransport(func(ctx context.Context, opts TransportOptions) Transport {
+					return &cancelingTransport{Transport: NewDefaultTransport(opts), cancel: cancel}
+				})
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			comm, err := NewCommunicate("Hello", "", "", "", "", "", 30, 30)
+			if err != nil {
+				t.Fatalf("NewCommunicate: %v", err)
+			}
+			comm.SetTokenGenerator(drm.NewTokenGenerator(nil))
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			tt.setup(t, comm, cancel)
+
+			started := time.Now()
+			_, err = collect(comm.Stream(ctx))
+			if err != context.Canceled {
+				t.Errorf("error = %v, want context.Canceled as is", err)
+			}
+			// The cancellation interrupts the request instead of waiting for a timeout
+			if elapsed := time.Since(started); elapsed > 10*time.Second {
+				t.Errorf("stream ended after %s, want it to end on cancel", elapsed)
+			}
+		})
+	}
+}
+
+// collect reads every chunk of the stream and returns them with the stream error.
+func collect(chunkChan <-chan types.TTSChunk, errChan <-chan error) ([]types.TTSChunk, error) {
+	var chunks []types.TTSChunk
+	for chunk := range chunkChan {
+		chunks = append(chunks, chunk)
+	}
+	return chunks, <-errChan
+}
+
+func TestStream(t *testing.T) {
+	const text = "Hello world from the fake service"
+
+	tests := []struct {
+		name  string
+		setup func(*ttstest.Server)
+
+		// wantErr is the sentinel the stream error must match, or nil on success
+		wantErr error
+
+		// check, if set, checks the stream error further
+		check func(t *testing.T, err error)
+	}{
+		{
+			name:  "plain synthesis",
+			setup: func(*ttstest.Server) {},
+		},
+		{
+			name:  "clock skew retry",
+			setup: func(s *ttstest.Server) { s.SetClockSkew(time.Hour) },
+		},
+		{
+			name:    "outdated version",
+			setup:   func(s *ttstest.Server) { s.Version = "0.0.0.0" },
+			wantErr: edgeerrors.ErrTokenRejected,
+		},
+		{
+			name:    "no audio",
+			setup:   func(s *ttstest.Server) { s.AddFault(ttstest.Fault{NoAudio: true}) },
+			wantErr: edgeerrors.ErrNoAudioReceived,
+		},
+		{
+			name:    "unknown path",
+			setup:   func(s *ttstest.Server) { s.AddFault(ttstest.Fault{UnknownPath: true}) },
+			wantErr: edgeerrors.ErrUnknownResponse,
+			check: func(t *testing.T, err error) {
+				var protocolErr *edgeerrors.ProtocolError
+				if !errors.As(err, &protocolErr) || protocolErr.Path != "unknown.path" {
+					t.Errorf("error = %v, want a *ProtocolError for unknown.path", err)
+				}
+			},
+		},
+		{
+			name:    "stall",
+			setup:   func(s *ttstest.Server) { s.AddFault(ttstest.Fault{CloseAfter: 2, Stall: true}) },
+			wantErr: edgeerrors.ErrTimeout,
+		},
+		{
+			name: "close code",
+			setup: func(s *ttstest.Server) {
+				s.AddFault(ttstest.Fault{CloseAfter: 2, CloseCode: edgeerrors.CloseTryAgainLater, CloseReason: "busy"})
+			},
+			wantErr: edgeerrors.ErrWebSocketError,
+			check: func(t *testing.T, err error) {
+				var closeErr *edgeerrors.CloseError
+				if !errors.As(err, &closeErr) || closeErr.Code != edgeerrors.CloseTryAgainLater || closeErr.Reason != "busy" {
+					t.Errorf("error = %v, want a *CloseError with code 1013 and reason busy", err)
+				}
+				if !edgeerrors.IsThrottled(err) {
+					t.Errorf("IsThrottled(%v) = false, want true", err)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := ttstest.NewServer()
+			defer server.Close()
+			tt.setup(server)
+
+			comm := newTestCommunicate(t, server, text)
+			chunks, err := collect(comm.Stream(context.Background()))
+
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error = %v, want %v", err, tt.wantErr)
+				}
+				var synthesisErr *edgeerrors.SynthesisError
+				if !errors.As(err, &synthesisErr) || synthesisErr.Chunk != 0 {
+					t.Errorf("error = %v, want a *SynthesisError for chunk 0", err)
+				}
+				if tt.check != nil {
+					tt.check(t, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Stream: %v", err)
+			}
+
+			var audio, words []types.TTSChunk
+			for _, chunk := range chunks {
+				switch chunk.Type {
+				case "audio":
+					audio = append(audio, chunk)
+				case "WordBoundary":
+					words = append(words, chunk)
+				}
+			}
+			wantWords := []string{"Hello", "world", "from", "the", "fake", "service"}
+			if len(audio) != len(wantWords) {
+				t.Errorf("audio chunks = %d, want %d", len(audio), len(wantWords))
+			}
+			if len(words) != len(wantWords) {
+				t.Fatalf("word boundaries = %d, want %d", len(words), len(wantWords))
+			}
+			for i, word := range words {
+				if word.Text != wantWords[i] {
+					t.Errorf("word %d = %q, want %q", i, word.Text, wantWords[i])
+				}
+				if want := float64(ttstest.WordDuration/100) * float64(i); word.Offset != want {
+					t.Errorf("word %d offset = %v, want %v", i, word.Offset, want)
+				}
+			}
+
+			requests := server.Requests()
+			if len(requests) != 1 {
+				t.Fatalf("requests = %d, want 1", len(requests))
+			}
+			if !strings.Contains(requests[0].SSML, text) {
+				t.Errorf("SSML %q does not contain the text", requests[0].SSML)
+			}
+		})
+	}
+}
+
+func TestStreamClockSkewAdjustsGenerator(t *testing.T) {
+	server := ttstest.NewServer()
+	defer server.Close()
+	server.SetClockSkew(time.Hour)
+
+	comm := newTestCommunicate(t, server, "Hello")
+	tokens := drm.NewTokenGenerator(nil)
+	comm.SetTokenGenerator(tokens)
+	if _, err := collect(comm.Stream(context.Background())); err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+
+	if skew := tokens.ClockSkew(); skew < time.Hour-5*time.Second || skew > time.Hour+5*time.Second {
+		t.Errorf("ClockSkew() = %s, want about %s", skew, time.Hour)
+	}
+}
+
+func TestStreamBatchedMetadata(t *testing.T) {
+	tests := []struct {
+		name     string
+		boundary string
+		visemes  bool
+
+		// want lists the type and text or viseme ID of the chunks, audio excluded
+		want []string
+	}{
+		{
+			name:     "words",
+			boundary: "WordBoundary",
+			want:     []string{"WordBoundary Hello", "WordBoundary big", "WordBoundary world"},
+		},
+		{
+			name:     "words and visemes",
+			boundary: "WordBoundary",
+			visemes:  true,
+			want: []string{
+				"WordBoundary Hello", "Viseme 1",
+				"WordBoundary big", "Viseme 2",
+				"WordBoundary world", "Viseme 3",
+			},
+		},
+		{
+			name:     "sentences and visemes",
+			boundary: "SentenceBoundary",
+			visemes:  true,
+			want:     []string{"SentenceBoundary Hello big world", "Viseme 1", "Viseme 2", "Viseme 3"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := ttstest.NewServer()
+			defer server.Close()
+			server.BatchMetadata = true
+
+			comm, err := NewCommunicate("Hello big world", "", "", "", "", "", 5, 5, tt.boundary)
+			if err != nil {
+				t.Fatalf("NewCommunicate: %v", err)
+			}
+			comm.SetEndpoint(server.Endpoint())
+			comm.SetTokenGenerator(drm.NewTokenGenerator(nil))
+			comm.SetVisemes(tt.visemes)
+			chunks, err := collect(comm.Stream(context.Background()))
+			if err != nil {
+				t.Fatalf("Stream: %v", err)
+			}
+
+			// The SessionEnd entry in the middle of the message is skipped
+			var got []string
+			audio := 0
+			for _, chunk := range chunks {
+				switch chunk.Type {
+				case "audio":
+					audio++
+				case "Viseme":
+					got = append(got, fmt.Sprintf("Viseme %d", chunk.VisemeID))
+				default:
+					got = append(got, chunk.Type+" "+chunk.Text)
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("chunks = %q, want %q", got, tt.want)
+			}
+			if audio != 3 {
+				t.Errorf("audio chunks = %d, want 3", audio)
+			}
+		})
+	}
+}
+
+// audioCollector is a metrics.Collector that sums the audio it receives.
+type audioCollector struct {
+	metrics.Nop
+
+	mu       sync.Mutex
+	bytes    int
+	duration time.Duration
+}
+
+func (c *audioCollector) AddAudio(bytes int, d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.bytes += bytes
+	c.duration += d
+}
+
+func TestStreamAudioMetrics(t *testing.T) {
+	const text = "Hello big world"
+	words := len(strings.Fields(text))
+	wordAudio := mp3.Silence(ttstest.WordDuration)
+
+	// Sizes that cut the frames, and their headers, in two
+	for _, size := range []int{0, 50, 7} {
+		t.Run(fmt.Sprintf("messages of %d bytes", size), func(t *testing.T) {
+			server := ttstest.NewServer()
+			defer server.Close()
+			server.AudioMessageSize = size
+
+			collector := &audioCollector{}
+			comm := newTestCommunicate(t, server, text)
+			comm.SetMetrics(collector)
+			if _, err := collect(comm.Stream(context.Background())); err != nil {
+				t.Fatalf("Stream: %v", err)
+			}
+
+			if want := words * len(wordAudio); collector.bytes != want {
+				t.Errorf("audio bytes = %d, want %d", collector.bytes, want)
+			}
+			if want := time.Duration(words) * mp3.Duration(wordAudio); collector.duration != want {
+				t.Errorf("audio duration = %s, want %s", collector.duration, want)
+			}
+		})
+	}
+}
+
+func TestBookmarks(t *testing.T) {
+	const text = `Hello <bookmark mark="greeting"/> world`
+
+	tests := []struct {
+		name      string
+		bookmarks bool
+		want      string
+	}{
+		{"disabled", false, `Hello &lt;bookmark mark=&quot;greeting&quot;/&gt; world`},
+		{"enabled", true, `Hello <bookmark mark='greeting'/> world`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := ttstest.NewServer()
+			defer server.Close()
+
+			comm := newTestCommunicate(t, server, text)
+			comm.SetBookmarks(tt.bookmarks)
+			if _, err := collect(comm.Stream(context.Background())); err != nil {
+				t.Fatalf("Stream: %v", err)
+			}
+
+			requests := server.Requests()
+			if len(requests) != 1 {
+				t.Fatalf("requests = %d, want 1", len(requests))
+			}
+			if !strings.Contains(requests[0].SSML, tt.want) {
+				t.Errorf("SSML %q does not contain %q", requests[0].SSML, tt.want)
+			}
+		})
+	}
+}
+
+func TestBookmarkTooLong(t *testing.T) {
+	server := ttstest.NewServer()
+	defer server.Close()
+
+	text := "Hello <bookmark mark='" + strings.Repeat("x", 1<<16) + "'/> world"
+	comm := newTestCommunicate(t, server, text)
+	comm.SetBookmarks(true)
+	if _, err := collect(comm.Stream(context.Background())); err == nil {
+		t.Fatal("Stream succeeded with a bookmark longer than a request")
+	}
+	if got := len(server.Requests()); got != 0 {
+		t.Errorf("requests = %d, want 0", got)
+	}
+}
+
+func TestStreamOffsetsAcrossRequests(t *testing.T) {
+	server := ttstest.NewServer()
+	defer server.Close()
+	server.BatchMetadata = true
+
+	// Longer than a WebSocket message, so that it is split in two requests. Long
+	// words keep the number of frames low.
+	var words []string
+	for i := 0; i < 80; i++ {
+		words = append(words, strings.Repeat(string(rune('a'+i%26)), 1000))
+	}
+	comm := newTestCommunicate(t, server, strings.Join(words, " "))
+	comm.SetVisemes(true)
+	chunks, err := collect(comm.Stream(context.Background()))
+	if err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+	if got := len(server.Requests()); got != 2 {
+		t.Fatalf("requests = %d, want 2", got)
+	}
+
+	// Offsets of the second request continue after the end of the first one, plus
+	// the padding the service adds to the end of the audio
+	const padding = 8_750_000
+	step := float64(ttstest.WordDuration / 100)
+	var offset, lastWord float64
+	var boundaries, jumps int
+	for _, chunk := range chunks {
+		switch chunk.Type {
+		case "WordBoundary":
+			if boundaries > 0 {
+				offset += step
+				if chunk.Offset == offset+padding {
+					offset += padding
+					jumps++
+				}
+			}
+			if chunk.Offset != offset {
+				t.Fatalf("word %d offset = %v, want %v", boundaries, chunk.Offset, offset)
+			}
+			lastWord = chunk.Offset
+			boundaries++
+		case "Viseme":
+			if chunk.Offset != lastWord {
+				t.Errorf("viseme offset = %v, want the offset %v of its word", chunk.Offset, lastWord)
+			}
+		}
+	}
+	if boundaries != len(words) || jumps != 1 {
+		t.Errorf("word boundaries = %d with %d jumps, want %d with 1", boundaries, jumps, len(words))
+	}
+}
+
+func TestHandshakeErrorRetryable(t *testing.T) {
+	// A TLS server whose certificate is not trusted, which logs the failed handshakes
+	tlsServer := httptest.NewUnstartedServer(http.NotFoundHandler())
+	tlsServer.Config.ErrorLog = log.New(io.Discard, "", 0)
+	tlsServer.StartTLS()
+	defer tlsServer.Close()
+
+	// A port nothing listens on
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	closedAddr := listener.Addr().String()
+	listener.Close()
+
+	tests := []struct {
+		name   string
+		wssURL string
+		want   bool
+	}{
+		{"connection refused", "ws://" + closedAddr + "/", true},
+		{"unknown authority", "wss" + strings.TrimPrefix(tlsServer.URL, "https") + "/", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			comm, err := NewCommunicate("Hello", "", "", "", "", "", 5, 5)
+			if err != nil {
+				t.Fatalf("NewCommunicate: %v", err)
+			}
+			comm.SetEndpoint(endpoint.Endpoint{WSSURL: tt.wssURL})
+			comm.SetTokenGenerator(drm.NewTokenGenerator(nil))
+
+			_, err = collect(comm.Stream(context.Background()))
+			var handshakeErr *edgeerrors.HandshakeError
+			if !errors.As(err, &handshakeErr) {
+				t.Fatalf("error = %v, want a *HandshakeError", err)
+			}
+			if got := edgeerrors.IsRetryable(err); got != tt.want {
+				t.Errorf("IsRetryable(%v) = %v, want %v", err, got, tt.want)
+			}
+		})
+	}
+}
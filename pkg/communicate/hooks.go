@@ -0,0 +1,97 @@
+package communicate
+
+import (
+	"time"
+
+	"github.com/difyz9/edge-tts-go/pkg/types"
+)
+
+// Hooks are optional callbacks on the synthesis lifecycle, for example to measure
+// latency, report progress or log. The text is split into chunks that are each
+// synthesized over their own connection; chunk is the index of the text chunk.
+//
+// Hooks are called from the goroutine that reads from the service, in order, and
+// should return quickly.
+type Hooks struct {
+	// OnConnect is called when the connection is established, with the time it took.
+	OnConnect func(chunk int, elapsed time.Duration)
+
+	// OnConfigSent is called when the speech.config message was sent.
+	OnConfigSent func(chunk int)
+
+	// OnTurnStart is called when the service starts synthesizing the chunk.
+	OnTurnStart func(chunk int)
+
+	// OnFirstAudio is called on the first audio data of the chunk, with the time
+	// elapsed since the SSML request was sent.
+	OnFirstAudio func(chunk int, elapsed time.Duration)
+
+	// OnBoundary is called for every WordBoundary and SentenceBoundary chunk.
+	OnBoundary func(chunk int, boundary types.TTSChunk)
+
+	// OnTurnEnd is called when the service finished synthesizing the chunk.
+	OnTurnEnd func(chunk int)
+
+	// OnChunkComplete is called when the chunk was fully received, with the time
+	// elapsed since the connection started.
+	OnChunkComplete func(chunk int, elapsed time.Duration)
+
+	// OnRetry is called when the connection is retried after err, for example
+	// after the clock skew was corrected.
+	OnRetry func(chunk int, err error)
+}
+
+// SetHooks sets the lifecycle hooks. It must be called before Stream.
+func (c *Communicate) SetHooks(h Hooks) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.hooks = h
+}
+
+func (h *Hooks) connect(chunk int, elapsed time.Duration) {
+	if h.OnConnect != nil {
+		h.OnConnect(chunk, elapsed)
+	}
+}
+
+func (h *Hooks) configSent(chunk int) {
+	if h.OnConfigSent != nil {
+		h.OnConfigSent(chunk)
+	}
+}
+
+func (h *Hooks) turnStart(chunk int) {
+	if h.OnTurnStart != nil {
+		h.OnTurnStart(chunk)
+	}
+}
+
+func (h *Hooks) firstAudio(chunk int, elapsed time.Duration) {
+	if h.OnFirstAudio != nil {
+		h.OnFirstAudio(chunk, elapsed)
+	}
+}
+
+func (h *Hooks) boundary(chunk int, boundary types.TTSChunk) {
+	if h.OnBoundary != nil {
+		h.OnBoundary(chunk, boundary)
+	}
+}
+
+func (h *Hooks) turnEnd(chunk int) {
+	if h.OnTurnEnd != nil {
+		h.OnTurnEnd(chunk)
+	}
+}
+
+func (h *Hooks) chunkComplete(chunk int, elapsed time.Duration) {
+	if h.OnChunkComplete != nil {
+		h.OnChunkComplete(chunk, elapsed)
+	}
+}
+
+func (h *Hooks) retry(chunk int, err error) {
+	if h.OnRetry != nil {
+		h.OnRetry(chunk, err)
+	}
+}
@@ -0,0 +1,82 @@
+package communicate
+
+import (
+	"context"
+
+	"github.com/difyz9/edge-tts-go/pkg/types"
+)
+
+// ChunkIterator is a pull-based alternative to Stream, created with Chunks.
+//
+//	it := comm.Chunks(ctx)
+//	defer it.Close()
+//	for it.Next() {
+//		chunk := it.Chunk()
+//		...
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type ChunkIterator struct {
+	c      *Communicate
+	ctx    context.Context
+	cancel context.CancelFunc
+	chunks <-chan types.TTSChunk
+	errs   <-chan error
+	chunk  types.TTSChunk
+	err    error
+	done   bool
+}
+
+// Chunks returns an iterator over the chunks of the stream. The stream starts on
+// the first call to Next, and Close stops it, so breaking out of the loop early
+// releases the connection as long as Close is called.
+func (c *Communicate) Chunks(ctx context.Context) *ChunkIterator {
+	ctx, cancel := context.WithCancel(ctx)
+	return &ChunkIterator{c: c, ctx: ctx, cancel: cancel}
+}
+
+// Next advances to the next chunk. It returns false when the stream ends or fails.
+func (it *ChunkIterator) Next() bool {
+	if it.done {
+		return false
+	}
+	if it.chunks == nil {
+		it.chunks, it.errs = it.c.Stream(it.ctx)
+	}
+
+	chunk, ok := <-it.chunks
+	if !ok {
+		it.done = true
+		it.err = <-it.errs
+		it.cancel()
+		return false
+	}
+	it.chunk = chunk
+	return true
+}
+
+// Chunk returns the current chunk.
+func (it *ChunkIterator) Chunk() types.TTSChunk {
+	return it.chunk
+}
+
+// Err returns the error that ended the stream, if any.
+func (it *ChunkIterator) Err() error {
+	return it.err
+}
+
+// Close stops the stream and closes its connection. It is safe to call Close
+// more than once, and after the stream ended.
+func (it *ChunkIterator) Close() error {
+	it.cancel()
+	if !it.done {
+		// Wait for the producer to notice the cancellation
+		if it.chunks != nil {
+			for range it.chunks {
+			}
+		}
+		it.done = true
+	}
+	return nil
+}
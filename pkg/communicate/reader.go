@@ -0,0 +1,54 @@
+package communicate
+
+import (
+	"context"
+	"io"
+
+	"github.com/difyz9/edge-tts-go/pkg/types"
+)
+
+// Reader returns a reader of the synthesized audio. The stream starts on the first
+// Read and only advances as the audio is read; Close stops it and closes its connection.
+func (c *Communicate) Reader(ctx context.Context) io.ReadCloser {
+	return c.ReaderWithMetadata(ctx, nil)
+}
+
+// ReaderWithMetadata is like Reader, and also calls fn with every metadata chunk
+// ("WordBoundary", "SentenceBoundary", "Bookmark", "Viseme") as it is reached,
+// from within Read. fn may be nil.
+func (c *Communicate) ReaderWithMetadata(ctx context.Context, fn func(types.TTSChunk)) io.ReadCloser {
+	return &audioReader{it: c.Chunks(ctx), fn: fn}
+}
+
+// audioReader reads the audio chunks of a ChunkIterator.
+type audioReader struct {
+	it  *ChunkIterator
+	fn  func(types.TTSChunk)
+	buf []byte
+}
+
+func (r *audioReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if !r.it.Next() {
+			if err := r.it.Err(); err != nil {
+				return 0, err
+			}
+			return 0, io.EOF
+		}
+
+		chunk := r.it.Chunk()
+		if chunk.Type == "audio" {
+			r.buf = chunk.Data
+		} else if r.fn != nil {
+			r.fn(chunk)
+		}
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func (r *audioReader) Close() error {
+	return r.it.Close()
+}
@@ -0,0 +1,120 @@
+package communicate
+
+import (
+	"context"
+	"crypto/tls"
+	"log/slog"
+
+	"github.com/difyz9/edge-tts-go/internal/websocket"
+	"github.com/difyz9/edge-tts-go/pkg/drm"
+	"github.com/difyz9/edge-tts-go/pkg/endpoint"
+	"github.com/difyz9/edge-tts-go/pkg/metrics"
+	"github.com/difyz9/edge-tts-go/pkg/record"
+	"github.com/difyz9/edge-tts-go/pkg/types"
+)
+
+// Transport is a connection to the TTS service, used for a single SSML request.
+//
+// ReceiveMessage returns one chunk at a time: "audio" chunks, metadata chunks
+// ("WordBoundary", "SentenceBoundary", "Bookmark", "Viseme"), informational
+// "turn.start" and "response" chunks, and a final "turn.end" chunk.
+type Transport interface {
+	Connect(ctx context.Context) error
+	SendCommandRequest(ttsConfig types.TTSConfig) error
+	SendSSMLRequest(partialText []byte, ttsConfig types.TTSConfig) error
+	ReceiveMessage() (types.TTSChunk, error)
+	Close() error
+}
+
+// TransportOptions are the settings of a Communicate that apply to the connection
+// of a single SSML request. They hold the values given to NewCommunicate, SetEndpoint,
+// SetTLSConfig, SetRecorder, SetLogger, SetMetrics, SetTokenGenerator and the
+// OnRetry hook of SetHooks.
+type TransportOptions struct {
+	// Chunk is the index of the text chunk sent over the connection.
+	Chunk int
+
+	Proxy          string
+	TLSConfig      *tls.Config
+	Endpoint       endpoint.Endpoint
+	ConnectTimeout int
+	ReceiveTimeout int
+
+	// Recorder records the frames of the connection, if not nil.
+	Recorder *record.Recorder
+
+	// Logger is the logger of the Communicate, with the voice and chunk attributes.
+	Logger *slog.Logger
+
+	// Metrics receives the clock skew corrections.
+	Metrics metrics.Collector
+
+	// TokenGenerator generates the Sec-MS-GEC tokens. Nil means the default one.
+	TokenGenerator *drm.TokenGenerator
+
+	// OnRetry, if not nil, must be called with the error that caused a connection retry.
+	OnRetry func(err error)
+}
+
+// TransportFactory creates a new Transport for each SSML request, with the settings
+// of the Communicate. ctx is the context of the stream.
+//
+// A custom transport that reaches the service itself must honor opts, so that the
+// setters of the Communicate keep working. Transports wrapping the default one can
+// pass opts to NewDefaultTransport unchanged.
+type TransportFactory func(ctx context.Context, opts TransportOptions) Transport
+
+// NewDefaultTransport creates the default WebSocket transport with opts, for
+// example to wrap it with instrumentation in a TransportFactory.
+func NewDefaultTransport(opts TransportOptions) Transport {
+	client := websocket.NewClient(opts.Proxy, opts.ConnectTimeout, opts.ReceiveTimeout)
+	client.SetEndpoint(opts.Endpoint)
+	client.SetTLSConfig(opts.TLSConfig)
+	client.SetRecorder(opts.Recorder)
+	client.SetMetrics(opts.Metrics)
+	client.SetTokenGenerator(opts.TokenGenerator)
+	client.SetLogger(opts.Logger)
+	if opts.OnRetry != nil {
+		client.SetRetryHook(opts.OnRetry)
+	}
+	return client
+}
+
+// SetTransport sets the factory of the transports used to reach the service, for
+// example to inject fakes or instrumentation wrappers. A nil factory restores the
+// default WebSocket transport. It must be called before Stream.
+func (c *Communicate) SetTransport(factory TransportFactory) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.newTransport = factory
+}
+
+// transport creates the Transport for the SSML request of the partial text at index.
+func (c *Communicate) transport(ctx context.Context, index int) Transport {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	opts := TransportOptions{
+		Chunk:          index,
+		Proxy:          c.proxy,
+		TLSConfig:      c.tlsConfig,
+		Endpoint:       c.endpoint,
+		ConnectTimeout: c.connectTimeout,
+		ReceiveTimeout: c.receiveTimeout,
+		Recorder:       c.recorder,
+		Logger:         c.logger.With("voice", c.ttsConfig.Voice, "chunk", index),
+		Metrics:        c.metrics,
+		TokenGenerator: c.tokens,
+	}
+	if c.hooks.OnRetry != nil {
+		hooks := c.hooks
+		opts.OnRetry = func(err error) {
+			hooks.retry(index, err)
+		}
+	}
+
+	if c.newTransport != nil {
+		return c.newTransport(ctx, opts)
+	}
+	return NewDefaultTransport(opts)
+}
@@ -0,0 +1,72 @@
+package communicate
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/difyz9/edge-tts-go/pkg/drm"
+	"github.com/difyz9/edge-tts-go/pkg/ttstest"
+	"github.com/difyz9/edge-tts-go/pkg/types"
+)
+
+// countingTransport wraps a Transport and counts the messages it receives.
+type countingTransport struct {
+	Transport
+	mu       *sync.Mutex
+	received map[string]int
+}
+
+func (t *countingTransport) ReceiveMessage() (types.TTSChunk, error) {
+	chunk, err := t.Transport.ReceiveMessage()
+	if err == nil {
+		t.mu.Lock()
+		t.received[chunk.Type]++
+		t.mu.Unlock()
+	}
+	return chunk, err
+}
+
+func TestWrappedDefaultTransport(t *testing.T) {
+	server := ttstest.NewServer()
+	defer server.Close()
+	server.SetClockSkew(time.Hour)
+
+	var mu sync.Mutex
+	received := map[string]int{}
+	var chunks []int
+	var retries int
+
+	comm := newTestCommunicate(t, server, "Hello wrapped world")
+	tokens := drm.NewTokenGenerator(nil)
+	comm.SetTokenGenerator(tokens)
+	comm.SetHooks(Hooks{OnRetry: func(int, error) { retries++ }})
+	comm.SetTransport(func(ctx context.Context, opts TransportOptions) Transport {
+		chunks = append(chunks, opts.Chunk)
+		return &countingTransport{Transport: NewDefaultTransport(opts), mu: &mu, received: received}
+	})
+
+	streamed, err := collect(comm.Stream(context.Background()))
+	if err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+
+	if len(chunks) != 1 || chunks[0] != 0 {
+		t.Errorf("factory called for chunks %v, want [0]", chunks)
+	}
+	if received["audio"] != 3 || received["WordBoundary"] != 3 || received["turn.end"] != 1 {
+		t.Errorf("received = %v, want 3 audio, 3 WordBoundary and 1 turn.end", received)
+	}
+	if len(streamed) != 6 {
+		t.Errorf("streamed chunks = %d, want 6", len(streamed))
+	}
+
+	// The endpoint, token generator and retry hook of the Communicate are honored
+	if retries != 1 {
+		t.Errorf("retries = %d, want 1", retries)
+	}
+	if skew := tokens.ClockSkew(); skew < time.Hour-5*time.Second || skew > time.Hour+5*time.Second {
+		t.Errorf("ClockSkew() = %s, want about %s", skew, time.Hour)
+	}
+}
@@ -0,0 +1,235 @@
+// Package drm handles DRM operations with clock skew correction.
+package drm
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/difyz9/edge-tts-go/internal/constants"
+	"github.com/difyz9/edge-tts-go/internal/logging"
+	"github.com/difyz9/edge-tts-go/pkg/errors"
+)
+
+// calibrationTolerance is the difference between a Date header and the corrected
+// clock that Calibrate ignores, as Date headers only have a one second precision.
+const calibrationTolerance = 2.0
+
+// MaxClockSkew is the largest clock skew that is corrected. Larger differences with
+// the server date are reported as errors, as the system clock must be fixed.
+const MaxClockSkew = 24 * time.Hour
+
+// SkewRetries is the number of times a request rejected because of clock skew is
+// retried after correcting it.
+const SkewRetries = 2
+
+// TokenGenerator generates Sec-MS-GEC tokens from a clock corrected by its own
+// clock skew. It is safe for concurrent use.
+type TokenGenerator struct {
+	now func() time.Time
+
+	// skewSeconds is the clock skew in seconds.
+	skewSeconds float64
+
+	// mu is used to protect skewSeconds.
+	mu sync.RWMutex
+}
+
+// NewTokenGenerator creates a new TokenGenerator reading the time from now, or
+// from time.Now if now is nil.
+func NewTokenGenerator(now func() time.Time) *TokenGenerator {
+	if now == nil {
+		now = time.Now
+	}
+	return &TokenGenerator{now: now}
+}
+
+// defaultGenerator is the TokenGenerator used by the package-level functions.
+var defaultGenerator = NewTokenGenerator(nil)
+
+// Default returns the TokenGenerator shared by the package-level functions, which
+// is used by every client that is not given its own.
+func Default() *TokenGenerator {
+	return defaultGenerator
+}
+
+// Or returns g, or the default TokenGenerator if g is nil.
+func Or(g *TokenGenerator) *TokenGenerator {
+	if g == nil {
+		return defaultGenerator
+	}
+	return g
+}
+
+// AdjClockSkewSeconds adjusts the clock skew in seconds in case the clock is off.
+func (g *TokenGenerator) AdjClockSkewSeconds(skewSeconds float64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.skewSeconds += skewSeconds
+}
+
+// ClockSkew returns the current clock skew.
+func (g *TokenGenerator) ClockSkew() time.Duration {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return time.Duration(g.skewSeconds * float64(time.Second))
+}
+
+// UnixTimestamp gets the current timestamp in Unix format with clock skew correction.
+func (g *TokenGenerator) UnixTimestamp() float64 {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return float64(g.now().UTC().Unix()) + g.skewSeconds
+}
+
+// GenerateSecMSGEC generates the Sec-MS-GEC token value for the given trusted client
+// token at the current corrected time.
+func (g *TokenGenerator) GenerateSecMSGEC(trustedClientToken string) string {
+	token, _ := g.GenerateSecMSGECWithTimestamp(trustedClientToken)
+	return token
+}
+
+// GenerateSecMSGECWithTimestamp is like GenerateSecMSGEC, but also returns the
+// corrected Unix timestamp of the token, to be passed to HandleClientResponseError
+// if the token is rejected.
+func (g *TokenGenerator) GenerateSecMSGECWithTimestamp(trustedClientToken string) (string, float64) {
+	timestamp := g.UnixTimestamp()
+	return GenerateSecMSGECAt(timestamp, trustedClientToken), timestamp
+}
+
+// HandleClientResponseError handles a client response error by adjusting the clock
+// skew to the server date, and returns the adjustment in seconds. sentAt is the
+// corrected Unix timestamp of the rejected token, as returned by
+// GenerateSecMSGECWithTimestamp. The adjustment is logged at debug level to logger,
+// which may be nil.
+//
+// If the rejected token was generated in the same 5-minute window as the server
+// date, it was not rejected because of clock skew and a *errors.TokenRejectedError
+// is returned. A skew larger than MaxClockSkew is not corrected.
+func (g *TokenGenerator) HandleClientResponseError(resp *http.Response, sentAt float64, logger *slog.Logger) (float64, error) {
+	if resp == nil {
+		return 0, errors.NewSkewAdjustmentError("no response")
+	}
+
+	serverDate := resp.Header.Get("Date")
+	if serverDate == "" {
+		return 0, errors.NewSkewAdjustmentError("no server date in headers")
+	}
+
+	serverDateParsed, err := ParseRFC2616Date(serverDate)
+	if err != nil {
+		return 0, errors.NewSkewAdjustmentError(fmt.Sprintf("failed to parse server date: %s", serverDate))
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	// The window of the token may have ended by the time the server checked it, in
+	// which case a new token is valid without adjusting the clock
+	if tokenWindow(sentAt) == tokenWindow(serverDateParsed) {
+		logging.Or(logger).Debug("token rejected with the clock in sync",
+			"server_date", serverDate,
+			"skew_seconds", g.skewSeconds)
+		return 0, &errors.TokenRejectedError{Skew: time.Duration(g.skewSeconds * float64(time.Second))}
+	}
+
+	adjustment := serverDateParsed - (float64(g.now().UTC().Unix()) + g.skewSeconds)
+	skew := time.Duration((g.skewSeconds + adjustment) * float64(time.Second))
+	if skew > MaxClockSkew || skew < -MaxClockSkew {
+		return 0, errors.NewSkewAdjustmentError(fmt.Sprintf(
+			"server date %s is %s away from the local clock, more than %s: check the system clock",
+			serverDate, skew, MaxClockSkew))
+	}
+
+	g.skewSeconds += adjustment
+	logging.Or(logger).Debug("clock skew adjusted",
+		"server_date", serverDate,
+		"adjustment_seconds", adjustment,
+		"skew_seconds", g.skewSeconds)
+	return adjustment, nil
+}
+
+// Calibrate adjusts the clock skew to the Date header of any response of the
+// service, so that tokens are valid before a request is rejected. It returns the
+// adjustment in seconds and whether the skew was adjusted: differences within the
+// precision of the header, skews larger than MaxClockSkew, or headers without a
+// valid date are ignored.
+func (g *TokenGenerator) Calibrate(header http.Header) (float64, bool) {
+	serverDate, err := ParseRFC2616Date(header.Get("Date"))
+	if err != nil {
+		return 0, false
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	adjustment := serverDate - (float64(g.now().UTC().Unix()) + g.skewSeconds)
+	if math.Abs(adjustment) <= calibrationTolerance || math.Abs(g.skewSeconds+adjustment) > MaxClockSkew.Seconds() {
+		return 0, false
+	}
+	g.skewSeconds += adjustment
+	return adjustment, true
+}
+
+// AdjClockSkewSeconds adjusts the clock skew of the default TokenGenerator.
+func AdjClockSkewSeconds(skewSeconds float64) {
+	defaultGenerator.AdjClockSkewSeconds(skewSeconds)
+}
+
+// ClockSkew returns the current clock skew of the default TokenGenerator.
+func ClockSkew() time.Duration {
+	return defaultGenerator.ClockSkew()
+}
+
+// GetUnixTimestamp gets the current timestamp in Unix format with the clock skew
+// correction of the default TokenGenerator.
+func GetUnixTimestamp() float64 {
+	return defaultGenerator.UnixTimestamp()
+}
+
+// ParseRFC2616Date parses an RFC 2616 date string into a Unix timestamp.
+func ParseRFC2616Date(date string) (float64, error) {
+	t, err := time.Parse(time.RFC1123, date)
+	if err != nil {
+		return 0, err
+	}
+	return float64(t.UTC().Unix()), nil
+}
+
+// HandleClientResponseError adjusts the clock skew of the default TokenGenerator
+// to the server date.
+func HandleClientResponseError(resp *http.Response, sentAt float64, logger *slog.Logger) (float64, error) {
+	return defaultGenerator.HandleClientResponseError(resp, sentAt, logger)
+}
+
+// GenerateSecMSGEC generates the Sec-MS-GEC token value for the given trusted client
+// token with the default TokenGenerator.
+func GenerateSecMSGEC(trustedClientToken string) string {
+	return defaultGenerator.GenerateSecMSGEC(trustedClientToken)
+}
+
+// GenerateSecMSGECAt generates the Sec-MS-GEC token value for the given Unix timestamp.
+func GenerateSecMSGECAt(ticks float64, trustedClientToken string) string {
+	// Switch to Windows file time epoch (1601-01-01 00:00:00 UTC) and round
+	// down to the nearest 5 minutes (300 seconds)
+	ticks = tokenWindow(ticks) * 300
+
+	// Convert the ticks to 100-nanosecond intervals (Windows file time format)
+	ticks *= constants.SToNS / 100
+
+	// Create the string to hash by concatenating the ticks and the trusted client token
+	strToHash := fmt.Sprintf("%.0f%s", ticks, trustedClientToken)
+
+	// Compute the SHA256 hash and return the uppercased hex digest
+	hash := sha256.Sum256([]byte(strToHash))
+	return fmt.Sprintf("%X", hash)
+}
+
+// tokenWindow returns the index of the 5-minute window, since the Windows file time
+// epoch, of a Unix timestamp. Tokens generated in the same window are identical.
+func tokenWindow(unix float64) float64 {
+	return math.Floor((unix + constants.WinEpoch) / 300)
+}
@@ -0,0 +1,232 @@
+package drm
+
+import (
+	stderrors "errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/difyz9/edge-tts-go/internal/constants"
+	"github.com/difyz9/edge-tts-go/pkg/errors"
+)
+
+// windowStart is the start of a 5-minute token window: 2024-01-01 00:00:00 UTC is
+// 133485408000000000 in Windows file time.
+var windowStart = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Tokens of the trusted client token in the window starting at windowStart and in
+// the next one.
+const (
+	windowToken     = "2AC0A57C1214B9458F8725BB7800499BB594EC29DDA83424BC14661707141F2F"
+	nextWindowToken = "B47E30F52B9A371287B3464E9CB67FF7FE2577AF052F9AE9E2F7DEB49B4B9C65"
+)
+
+// fixedClock returns a clock that always reads t.
+func fixedClock(t time.Time) func() time.Time {
+	return func() time.Time { return t }
+}
+
+// dateResponse returns a response with a Date header set to t.
+func dateResponse(t time.Time) *http.Response {
+	return &http.Response{Header: http.Header{"Date": {t.UTC().Format(http.TimeFormat)}}}
+}
+
+func TestGenerateSecMSGECWindows(t *testing.T) {
+	tests := []struct {
+		name   string
+		offset time.Duration
+		want   string
+	}{
+		{"window start", 0, windowToken},
+		{"within window", 150 * time.Second, windowToken},
+		{"window end", 299 * time.Second, windowToken},
+		{"next window", 300 * time.Second, nextWindowToken},
+		{"within next window", 599 * time.Second, nextWindowToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewTokenGenerator(fixedClock(windowStart.Add(tt.offset)))
+			if got := g.GenerateSecMSGEC(constants.TrustedClientToken); got != tt.want {
+				t.Errorf("GenerateSecMSGEC() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	before := GenerateSecMSGECAt(float64(windowStart.Unix()-1), constants.TrustedClientToken)
+	if before == windowToken {
+		t.Errorf("token one second before the window = window token")
+	}
+}
+
+func TestTokenWindow(t *testing.T) {
+	// The Unix epoch is 11644473600 seconds after the Windows epoch
+	if got, want := tokenWindow(0), float64(constants.WinEpoch/300); got != want {
+		t.Errorf("tokenWindow(0) = %v, want %v", got, want)
+	}
+	start := float64(windowStart.Unix())
+	if tokenWindow(start) != tokenWindow(start+299) {
+		t.Errorf("tokenWindow differs within a window")
+	}
+	if tokenWindow(start+300) != tokenWindow(start)+1 {
+		t.Errorf("tokenWindow(start+300) is not the next window")
+	}
+}
+
+func TestHandleClientResponseError(t *testing.T) {
+	tests := []struct {
+		name       string
+		serverDate time.Time
+		wantSkew   time.Duration
+		wantErr    error
+	}{
+		{"server ahead", windowStart.Add(time.Hour), time.Hour, nil},
+		{"server behind", windowStart.Add(-90 * time.Minute), -90 * time.Minute, nil},
+		{"same window", windowStart.Add(10 * time.Second), 0, errors.ErrTokenRejected},
+		{"too far", windowStart.Add(MaxClockSkew + time.Hour), 0, errors.ErrSkewAdjustmentError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewTokenGenerator(fixedClock(windowStart))
+			adjustment, err := g.HandleClientResponseError(dateResponse(tt.serverDate), g.UnixTimestamp(), nil)
+			if !stderrors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if got := time.Duration(adjustment * float64(time.Second)); got != tt.wantSkew {
+				t.Errorf("adjustment = %s, want %s", got, tt.wantSkew)
+			}
+			if got := g.ClockSkew(); got != tt.wantSkew {
+				t.Errorf("ClockSkew() = %s, want %s", got, tt.wantSkew)
+			}
+		})
+	}
+}
+
+func TestHandleClientResponseErrorFixesToken(t *testing.T) {
+	g := NewTokenGenerator(fixedClock(windowStart.Add(-time.Hour)))
+	if _, err := g.HandleClientResponseError(dateResponse(windowStart), g.UnixTimestamp(), nil); err != nil {
+		t.Fatalf("HandleClientResponseError: %v", err)
+	}
+	if got := g.GenerateSecMSGEC(constants.TrustedClientToken); got != windowToken {
+		t.Errorf("GenerateSecMSGEC() = %s, want the token of the server clock %s", got, windowToken)
+	}
+
+	// The token is rejected again in the same window: the clock is not the cause
+	_, err := g.HandleClientResponseError(dateResponse(windowStart), g.UnixTimestamp(), nil)
+	var rejected *errors.TokenRejectedError
+	if !stderrors.As(err, &rejected) {
+		t.Fatalf("error = %v, want a *TokenRejectedError", err)
+	}
+	if rejected.Skew != time.Hour {
+		t.Errorf("TokenRejectedError.Skew = %s, want %s", rejected.Skew, time.Hour)
+	}
+}
+
+func TestHandleClientResponseErrorWindowEdge(t *testing.T) {
+	tests := []struct {
+		name       string
+		sentAt     time.Duration
+		serverDate time.Duration
+		wantErr    error
+	}{
+		// The token of the first window reached the server in the next one: a new
+		// token is valid, so the request is retried
+		{"window ended in transit", 299 * time.Second, 300 * time.Second, nil},
+		{"same window", 299 * time.Second, 299 * time.Second, errors.ErrTokenRejected},
+		{"next window", 300 * time.Second, 300 * time.Second, errors.ErrTokenRejected},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The response is handled at the start of the next window
+			g := NewTokenGenerator(fixedClock(windowStart.Add(300 * time.Second)))
+			sentAt := float64(windowStart.Add(tt.sentAt).Unix())
+			adjustment, err := g.HandleClientResponseError(dateResponse(windowStart.Add(tt.serverDate)), sentAt, nil)
+			if !stderrors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && g.GenerateSecMSGEC(constants.TrustedClientToken) != nextWindowToken {
+				t.Errorf("GenerateSecMSGEC() after adjusting by %vs is not the token of the server window", adjustment)
+			}
+		})
+	}
+}
+
+func TestHandleClientResponseErrorInvalidDate(t *testing.T) {
+	tests := []struct {
+		name string
+		resp *http.Response
+	}{
+		{"no response", nil},
+		{"no date", &http.Response{Header: http.Header{}}},
+		{"invalid date", &http.Response{Header: http.Header{"Date": {"yesterday"}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewTokenGenerator(fixedClock(windowStart))
+			if _, err := g.HandleClientResponseError(tt.resp, g.UnixTimestamp(), nil); !errors.IsSkewAdjustmentError(err) {
+				t.Errorf("error = %v, want a skew adjustment error", err)
+			}
+			if got := g.ClockSkew(); got != 0 {
+				t.Errorf("ClockSkew() = %s, want 0", got)
+			}
+		})
+	}
+}
+
+func TestCalibrate(t *testing.T) {
+	tests := []struct {
+		name       string
+		serverDate time.Time
+		wantSkew   time.Duration
+		wantOK     bool
+	}{
+		{"ahead", windowStart.Add(10 * time.Second), 10 * time.Second, true},
+		{"behind", windowStart.Add(-time.Minute), -time.Minute, true},
+		{"within tolerance", windowStart.Add(2 * time.Second), 0, false},
+		{"too far", windowStart.Add(-MaxClockSkew - time.Second), 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewTokenGenerator(fixedClock(windowStart))
+			header := dateResponse(tt.serverDate).Header
+			adjustment, ok := g.Calibrate(header)
+			if ok != tt.wantOK {
+				t.Fatalf("Calibrate() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if got := time.Duration(adjustment * float64(time.Second)); got != tt.wantSkew {
+				t.Errorf("adjustment = %s, want %s", got, tt.wantSkew)
+			}
+			if got := g.ClockSkew(); got != tt.wantSkew {
+				t.Errorf("ClockSkew() = %s, want %s", got, tt.wantSkew)
+			}
+		})
+	}
+
+	g := NewTokenGenerator(fixedClock(windowStart))
+	if _, ok := g.Calibrate(http.Header{}); ok {
+		t.Errorf("Calibrate() without a Date header adjusted the skew")
+	}
+}
+
+func TestTokenGeneratorsDoNotShareSkew(t *testing.T) {
+	a := NewTokenGenerator(fixedClock(windowStart))
+	b := NewTokenGenerator(fixedClock(windowStart))
+	defaultSkew := ClockSkew()
+
+	if _, err := a.HandleClientResponseError(dateResponse(windowStart.Add(time.Hour)), a.UnixTimestamp(), nil); err != nil {
+		t.Fatalf("HandleClientResponseError: %v", err)
+	}
+	b.AdjClockSkewSeconds(-30)
+
+	if got := a.ClockSkew(); got != time.Hour {
+		t.Errorf("a.ClockSkew() = %s, want %s", got, time.Hour)
+	}
+	if got := b.ClockSkew(); got != -30*time.Second {
+		t.Errorf("b.ClockSkew() = %s, want %s", got, -30*time.Second)
+	}
+	if got := ClockSkew(); got != defaultSkew {
+		t.Errorf("default ClockSkew() = %s, want %s", got, defaultSkew)
+	}
+	if a.GenerateSecMSGEC(constants.TrustedClientToken) == b.GenerateSecMSGEC(constants.TrustedClientToken) {
+		t.Errorf("generators with different skews generated the same token")
+	}
+}
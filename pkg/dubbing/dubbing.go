@@ -0,0 +1,167 @@
+// Package dubbing synthesizes speech for existing subtitle cues and assembles it
+// into a single audio track that follows the subtitle timings.
+package dubbing
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/difyz9/edge-tts-go/internal/mp3"
+	"github.com/difyz9/edge-tts-go/pkg/communicate"
+	"github.com/difyz9/edge-tts-go/pkg/drm"
+	"github.com/difyz9/edge-tts-go/pkg/endpoint"
+	"github.com/difyz9/edge-tts-go/pkg/metrics"
+	"github.com/difyz9/edge-tts-go/pkg/ratelimit"
+	"github.com/difyz9/edge-tts-go/pkg/submaker"
+)
+
+// DefaultMaxRate is the default maximum rate increase, in percent, used to fit a cue into its window.
+const DefaultMaxRate = 100
+
+// Options represents the synthesis options used for every cue.
+type Options struct {
+	Voice          string
+	Volume         string
+	Pitch          string
+	Proxy          string
+	TLSConfig      *tls.Config
+	ConnectTimeout int
+	ReceiveTimeout int
+
+	// MaxRate is the maximum rate increase, in percent, applied to a cue whose
+	// speech is longer than its window. Defaults to DefaultMaxRate.
+	MaxRate int
+
+	// Endpoint is the endpoint of the service. The zero value uses the Microsoft Edge endpoint.
+	Endpoint endpoint.Endpoint
+
+	// Logger receives debug logs of the synthesis of every cue. Optional.
+	Logger *slog.Logger
+
+	// Metrics receives the synthesis metrics of every cue. Optional.
+	Metrics metrics.Collector
+
+	// Limiter limits the requests sent for the cues. Optional.
+	Limiter *ratelimit.Limiter
+
+	// TokenGenerator generates the Sec-MS-GEC tokens and holds the clock skew.
+	// Defaults to the generator shared by the process.
+	TokenGenerator *drm.TokenGenerator
+}
+
+// Dub synthesizes each cue and writes a single MP3 track to w. Silence is inserted
+// so that every cue starts at its subtitle start time, and the rate of each cue is
+// increased, up to opts.MaxRate, so that its speech fits between Start and End.
+//
+// Speech that still does not fit pushes the following cues back. The returned cues
+// hold the actual placement of each cue in the written track.
+//
+// The formatting markup of the cues, such as <i> tags, is not spoken, and cues
+// without any text once it is removed are skipped.
+func Dub(ctx context.Context, cues []submaker.Subtitle, opts Options, w io.Writer) ([]submaker.Subtitle, error) {
+	if opts.MaxRate <= 0 {
+		opts.MaxRate = DefaultMaxRate
+	}
+
+	placed := make([]submaker.Subtitle, 0, len(cues))
+	var cursor time.Duration
+
+	for _, cue := range cues {
+		if cue.PlainText() == "" {
+			continue
+		}
+
+		audio, err := synthesizeCue(ctx, cue, opts)
+		if err != nil {
+			return placed, fmt.Errorf("cue %d: %w", cue.Index, err)
+		}
+
+		// Pad with silence up to the start of the cue
+		if cue.Start > cursor {
+			silence := mp3.Silence(cue.Start - cursor)
+			if _, err := w.Write(silence); err != nil {
+				return placed, err
+			}
+			cursor += mp3.Duration(silence)
+		}
+
+		if _, err := w.Write(audio); err != nil {
+			return placed, err
+		}
+
+		start := cursor
+		cursor += mp3.Duration(audio)
+		placed = append(placed, submaker.Subtitle{
+			Index:   len(placed) + 1,
+			Start:   start,
+			End:     cursor,
+			Content: cue.Content,
+		})
+	}
+
+	return placed, nil
+}
+
+// synthesizeCue synthesizes a cue at the default rate and, if the speech is longer
+// than the cue window, synthesizes it again at a faster rate.
+func synthesizeCue(ctx context.Context, cue submaker.Subtitle, opts Options) ([]byte, error) {
+	text := cue.PlainText()
+	audio, err := synthesize(ctx, text, "+0%", opts)
+	if err != nil {
+		return nil, err
+	}
+
+	window := cue.End - cue.Start
+	duration := mp3.Duration(audio)
+	if window <= 0 || duration <= window {
+		return audio, nil
+	}
+
+	rate := fitRate(duration, window, opts.MaxRate)
+	return synthesize(ctx, text, fmt.Sprintf("%+d%%", rate), opts)
+}
+
+// synthesize synthesizes text at the given rate and returns the MP3 audio.
+func synthesize(ctx context.Context, text, rate string, opts Options) ([]byte, error) {
+	comm, err := communicate.NewCommunicate(
+		text,
+		opts.Voice,
+		rate,
+		opts.Volume,
+		opts.Pitch,
+		opts.Proxy,
+		opts.ConnectTimeout,
+		opts.ReceiveTimeout,
+	)
+	if err != nil {
+		return nil, err
+	}
+	comm.SetEndpoint(opts.Endpoint)
+	comm.SetTLSConfig(opts.TLSConfig)
+	comm.SetLogger(opts.Logger)
+	comm.SetMetrics(opts.Metrics)
+	comm.SetLimiter(opts.Limiter)
+	comm.SetTokenGenerator(opts.TokenGenerator)
+
+	var buf bytes.Buffer
+	if err := comm.StreamToWriter(ctx, &buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// fitRate returns the rate increase, in percent, needed to shorten speech of the
+// given duration to the window, capped at maxRate.
+func fitRate(duration, window time.Duration, maxRate int) int {
+	rate := int(math.Ceil((float64(duration)/float64(window) - 1) * 100))
+	if rate > maxRate {
+		rate = maxRate
+	}
+	return rate
+}
@@ -0,0 +1,87 @@
+package dubbing
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/difyz9/edge-tts-go/internal/mp3"
+	"github.com/difyz9/edge-tts-go/pkg/drm"
+	"github.com/difyz9/edge-tts-go/pkg/submaker"
+	"github.com/difyz9/edge-tts-go/pkg/ttstest"
+)
+
+func TestDub(t *testing.T) {
+	server := ttstest.NewServer()
+	defer server.Close()
+
+	cues := []submaker.Subtitle{
+		{Index: 1, Start: time.Second, End: 2 * time.Second, Content: "Hello <i>World</i>"},
+		{Index: 2, Start: 3 * time.Second, End: 4 * time.Second, Content: `{\an8}<font color="red"></font>`},
+		{Index: 3, Start: 5 * time.Second, End: 6 * time.Second, Content: "<v Speaker>Fish &amp; chips</v>"},
+	}
+
+	var buf bytes.Buffer
+	placed, err := Dub(context.Background(), cues, Options{
+		Endpoint:       server.Endpoint(),
+		TokenGenerator: drm.NewTokenGenerator(nil),
+		ConnectTimeout: 5,
+		ReceiveTimeout: 5,
+	}, &buf)
+	if err != nil {
+		t.Fatalf("Dub: %v", err)
+	}
+
+	// The cue without text is skipped
+	requests := server.Requests()
+	if len(requests) != 2 {
+		t.Fatalf("requests = %d, want 2", len(requests))
+	}
+	for i, want := range []string{"Hello World", "Fish &amp; chips"} {
+		ssml := requests[i].SSML
+		if !strings.Contains(ssml, want) {
+			t.Errorf("request %d SSML %q does not contain %q", i, ssml, want)
+		}
+		if strings.Contains(ssml, "&lt;") || strings.Contains(ssml, `{\`) {
+			t.Errorf("request %d SSML %q contains subtitle markup", i, ssml)
+		}
+	}
+
+	if len(placed) != 2 {
+		t.Fatalf("placed cues = %d, want 2", len(placed))
+	}
+	for i, cue := range placed {
+		want := cues[2*i]
+		if cue.Content != want.Content {
+			t.Errorf("placed cue %d content = %q, want %q", i, cue.Content, want.Content)
+		}
+		// Silence is padded in whole MP3 frames
+		if d := cue.Start - want.Start; d < -50*time.Millisecond || d > 50*time.Millisecond {
+			t.Errorf("placed cue %d starts at %s, want about %s", i, cue.Start, want.Start)
+		}
+	}
+
+	if d := mp3.Duration(buf.Bytes()); d != placed[1].End {
+		t.Errorf("track duration = %s, want %s", d, placed[1].End)
+	}
+}
+
+func TestFitRate(t *testing.T) {
+	tests := []struct {
+		duration, window time.Duration
+		maxRate          int
+		want             int
+	}{
+		{2 * time.Second, time.Second, 200, 100},
+		{1500 * time.Millisecond, time.Second, 100, 50},
+		{1001 * time.Millisecond, time.Second, 100, 1},
+		{5 * time.Second, time.Second, 100, 100},
+	}
+	for _, tt := range tests {
+		if got := fitRate(tt.duration, tt.window, tt.maxRate); got != tt.want {
+			t.Errorf("fitRate(%s, %s, %d) = %d, want %d", tt.duration, tt.window, tt.maxRate, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,252 @@
+// Package endpoint describes the TTS service endpoint and the headers sent to it.
+package endpoint
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/difyz9/edge-tts-go/internal/constants"
+)
+
+// Endpoint represents the configuration of the TTS service endpoint.
+// The zero value of each field falls back to the Microsoft Edge defaults.
+//
+// Setting SubscriptionKey or AuthToken switches to the Azure Speech service, which
+// speaks the same protocol but authenticates with a header instead of the
+// TrustedClientToken and Sec-MS-GEC token.
+type Endpoint struct {
+	// WSSURL is the WebSocket URL of the synthesis service, without query.
+	WSSURL string
+
+	// VoiceListURL is the URL of the voice list, without query.
+	VoiceListURL string
+
+	// TrustedClientToken is the token used to authenticate with the service.
+	TrustedClientToken string
+
+	// ClientVersion is the full Chromium version of Microsoft Edge, e.g. "130.0.2849.68".
+	// It is used in the Sec-MS-GEC-Version query parameter and the User-Agent header.
+	ClientVersion string
+
+	// UserAgent overrides the User-Agent header derived from ClientVersion.
+	UserAgent string
+
+	// Header holds extra headers sent with every request. They override the default headers.
+	Header http.Header
+
+	// SubscriptionKey is the Azure Speech resource key, sent in the
+	// Ocp-Apim-Subscription-Key header.
+	SubscriptionKey string
+
+	// AuthToken is an Azure Speech access token, sent in the Authorization header.
+	AuthToken string
+}
+
+// Azure returns the Azure Speech endpoint of the given region, e.g. "westeurope",
+// authenticated with a resource key. It fails if the region or the key is empty,
+// as the endpoint would otherwise fall back to the Microsoft Edge authentication.
+func Azure(region, subscriptionKey string) (Endpoint, error) {
+	if subscriptionKey == "" {
+		return Endpoint{}, errors.New("azure: empty subscription key")
+	}
+	e, err := azure(region)
+	if err != nil {
+		return Endpoint{}, err
+	}
+	e.SubscriptionKey = subscriptionKey
+	return e, nil
+}
+
+// AzureToken returns the Azure Speech endpoint of the given region, authenticated
+// with an access token obtained from the issueToken endpoint. It fails if the region
+// or the token is empty.
+func AzureToken(region, token string) (Endpoint, error) {
+	if token == "" {
+		return Endpoint{}, errors.New("azure: empty access token")
+	}
+	e, err := azure(region)
+	if err != nil {
+		return Endpoint{}, err
+	}
+	e.AuthToken = token
+	return e, nil
+}
+
+// azure returns the Azure Speech endpoint of the given region, without credentials.
+func azure(region string) (Endpoint, error) {
+	if region == "" {
+		return Endpoint{}, errors.New("azure: empty region")
+	}
+	host := region + ".tts.speech.microsoft.com"
+	return Endpoint{
+		WSSURL:       "wss://" + host + "/cognitiveservices/websocket/v1",
+		VoiceListURL: "https://" + host + "/cognitiveservices/voices/list",
+	}, nil
+}
+
+// Default returns the Microsoft Edge endpoint.
+func Default() Endpoint {
+	return Endpoint{
+		WSSURL:             "wss://" + constants.BaseURL + "/edge/v1",
+		VoiceListURL:       "https://" + constants.BaseURL + "/voices/list",
+		TrustedClientToken: constants.TrustedClientToken,
+		ClientVersion:      constants.ChromiumFullVersion,
+	}
+}
+
+// UsesDRM reports whether the endpoint authenticates with the TrustedClientToken and
+// the Sec-MS-GEC token, like Microsoft Edge, rather than with Azure credentials.
+func (e Endpoint) UsesDRM() bool {
+	return e.SubscriptionKey == "" && e.AuthToken == ""
+}
+
+// withDefaults returns the endpoint with the empty fields set to their defaults.
+func (e Endpoint) withDefaults() Endpoint {
+	d := Default()
+	if e.WSSURL == "" {
+		e.WSSURL = d.WSSURL
+	}
+	if e.VoiceListURL == "" {
+		e.VoiceListURL = d.VoiceListURL
+	}
+	if e.TrustedClientToken == "" {
+		e.TrustedClientToken = d.TrustedClientToken
+	}
+	if e.ClientVersion == "" {
+		e.ClientVersion = d.ClientVersion
+	}
+	return e
+}
+
+// Token returns the trusted client token.
+func (e Endpoint) Token() string {
+	return e.withDefaults().TrustedClientToken
+}
+
+// SecMSGECVersion returns the value of the Sec-MS-GEC-Version query parameter.
+func (e Endpoint) SecMSGECVersion() string {
+	return "1-" + e.withDefaults().ClientVersion
+}
+
+// majorVersion returns the major version of ClientVersion.
+func (e Endpoint) majorVersion() string {
+	major, _, _ := strings.Cut(e.withDefaults().ClientVersion, ".")
+	return major
+}
+
+// userAgent returns the User-Agent header.
+func (e Endpoint) userAgent() string {
+	if e.UserAgent != "" {
+		return e.UserAgent
+	}
+	major := e.majorVersion()
+	return "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36" +
+		" (KHTML, like Gecko) Chrome/" + major + ".0.0.0 Safari/537.36" +
+		" Edg/" + major + ".0.0.0"
+}
+
+// SynthesisURL returns the WebSocket URL for a new connection. The Azure Speech
+// service takes the connection ID as a header instead, see WSSHeaders.
+func (e Endpoint) SynthesisURL(secMSGEC, connectionID string) string {
+	e = e.withDefaults()
+	if !e.UsesDRM() {
+		return e.WSSURL
+	}
+	return appendQuery(e.WSSURL,
+		"TrustedClientToken", e.TrustedClientToken,
+		"Sec-MS-GEC", secMSGEC,
+		"Sec-MS-GEC-Version", e.SecMSGECVersion(),
+		"ConnectionId", connectionID)
+}
+
+// VoicesURL returns the URL of the voice list.
+func (e Endpoint) VoicesURL(secMSGEC string) string {
+	e = e.withDefaults()
+	if !e.UsesDRM() {
+		return e.VoiceListURL
+	}
+	return appendQuery(e.VoiceListURL,
+		"trustedclienttoken", e.TrustedClientToken,
+		"Sec-MS-GEC", secMSGEC,
+		"Sec-MS-GEC-Version", e.SecMSGECVersion())
+}
+
+// WSSHeaders returns the headers used in WebSocket requests for the given connection.
+func (e Endpoint) WSSHeaders(connectionID string) http.Header {
+	header := http.Header{}
+	if !e.UsesDRM() {
+		header.Set("User-Agent", e.userAgent())
+		header.Set("X-ConnectionId", connectionID)
+		return e.merge(e.authorize(header))
+	}
+
+	for k, v := range constants.WSSHeaders {
+		header.Set(k, v)
+	}
+	header.Set("User-Agent", e.userAgent())
+	return e.merge(header)
+}
+
+// VoiceHeaders returns the headers used in voice list requests.
+func (e Endpoint) VoiceHeaders() http.Header {
+	header := http.Header{}
+	if !e.UsesDRM() {
+		header.Set("User-Agent", e.userAgent())
+		return e.merge(e.authorize(header))
+	}
+
+	for k, v := range constants.VoiceHeaders {
+		header.Set(k, v)
+	}
+	major := e.majorVersion()
+	header.Set("Sec-CH-UA", "\" Not;A Brand\";v=\"99\", \"Microsoft Edge\";v=\""+major+"\","+
+		" \"Chromium\";v=\""+major+"\"")
+	header.Set("User-Agent", e.userAgent())
+	if u, err := url.Parse(e.withDefaults().VoiceListURL); err == nil {
+		header.Set("Authority", u.Host)
+	}
+	return e.merge(header)
+}
+
+// authorize adds the Azure Speech credentials to the headers.
+func (e Endpoint) authorize(header http.Header) http.Header {
+	if e.SubscriptionKey != "" {
+		header.Set("Ocp-Apim-Subscription-Key", e.SubscriptionKey)
+	}
+	if e.AuthToken != "" {
+		header.Set("Authorization", "Bearer "+e.AuthToken)
+	}
+	return header
+}
+
+// merge overrides the given headers with the extra headers of the endpoint.
+func (e Endpoint) merge(header http.Header) http.Header {
+	for k, values := range e.Header {
+		header.Del(k)
+		for _, v := range values {
+			header.Add(k, v)
+		}
+	}
+	return header
+}
+
+// appendQuery appends the key/value pairs to the query of rawURL, keeping their order.
+func appendQuery(rawURL string, pairs ...string) string {
+	var sb strings.Builder
+	sb.WriteString(rawURL)
+
+	sep := "?"
+	if strings.Contains(rawURL, "?") {
+		sep = "&"
+	}
+	for i := 0; i+1 < len(pairs); i += 2 {
+		sb.WriteString(sep)
+		sb.WriteString(url.QueryEscape(pairs[i]))
+		sb.WriteString("=")
+		sb.WriteString(url.QueryEscape(pairs[i+1]))
+		sep = "&"
+	}
+	return sb.String()
+}
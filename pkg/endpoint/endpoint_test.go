@@ -0,0 +1,49 @@
+package endpoint
+
+import "testing"
+
+func TestAzure(t *testing.T) {
+	tests := []struct {
+		name          string
+		region, key   string
+		wantErr       bool
+		wantSynthesis string
+	}{
+		{"key", "westeurope", "secret", false, "wss://westeurope.tts.speech.microsoft.com/cognitiveservices/websocket/v1"},
+		{"empty key", "westeurope", "", true, ""},
+		{"empty region", "", "secret", true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := Azure(tt.region, tt.key)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Azure(%q, %q) = %+v, want an error", tt.region, tt.key, e)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Azure(%q, %q): %v", tt.region, tt.key, err)
+			}
+			if e.UsesDRM() {
+				t.Error("UsesDRM() = true, want false")
+			}
+			if got := e.SynthesisURL("token", "id"); got != tt.wantSynthesis {
+				t.Errorf("SynthesisURL() = %q, want %q", got, tt.wantSynthesis)
+			}
+		})
+	}
+}
+
+func TestAzureToken(t *testing.T) {
+	if _, err := AzureToken("westeurope", ""); err == nil {
+		t.Error("AzureToken with an empty token succeeded")
+	}
+	e, err := AzureToken("westeurope", "token")
+	if err != nil {
+		t.Fatalf("AzureToken: %v", err)
+	}
+	if e.UsesDRM() {
+		t.Error("UsesDRM() = true, want false")
+	}
+}
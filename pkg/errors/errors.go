@@ -25,16 +25,23 @@ var (
 
 	// ErrSkewAdjustmentError is raised when an error occurs while adjusting the clock skew.
 	ErrSkewAdjustmentError = fmt.Errorf("%w: skew adjustment error", ErrEdgeTTS)
+
+	// ErrTimeout is raised when the handshake or a receive does not complete in time.
+	ErrTimeout = fmt.Errorf("%w: timeout", ErrEdgeTTS)
+
+	// ErrTokenRejected is raised when the service rejects the Sec-MS-GEC token although
+	// the clock is in sync, usually because the client version is outdated.
+	ErrTokenRejected = fmt.Errorf("%w: token rejected", ErrEdgeTTS)
 )
 
 // NewUnknownResponseError creates a new unknown response error with a custom message.
 func NewUnknownResponseError(msg string) error {
-	return fmt.Errorf("%w: %s", ErrUnknownResponse, msg)
+	return &ProtocolError{Err: ErrUnknownResponse, Msg: msg}
 }
 
 // NewUnexpectedResponseError creates a new unexpected response error with a custom message.
 func NewUnexpectedResponseError(msg string) error {
-	return fmt.Errorf("%w: %s", ErrUnexpectedResponse, msg)
+	return &ProtocolError{Err: ErrUnexpectedResponse, Msg: msg}
 }
 
 // NewNoAudioReceivedError creates a new no audio received error with a custom message.
@@ -52,6 +59,11 @@ func NewSkewAdjustmentError(msg string) error {
 	return fmt.Errorf("%w: %s", ErrSkewAdjustmentError, msg)
 }
 
+// NewTimeoutError creates a new timeout error with a custom message.
+func NewTimeoutError(msg string) error {
+	return &TimeoutError{Msg: msg}
+}
+
 // IsEdgeTTSError checks if the error is an edge-tts error.
 func IsEdgeTTSError(err error) bool {
 	return errors.Is(err, ErrEdgeTTS)
@@ -81,3 +93,13 @@ func IsWebSocketError(err error) bool {
 func IsSkewAdjustmentError(err error) bool {
 	return errors.Is(err, ErrSkewAdjustmentError)
 }
+
+// IsTimeoutError checks if the error is a timeout error.
+func IsTimeoutError(err error) bool {
+	return errors.Is(err, ErrTimeout)
+}
+
+// IsTokenRejectedError checks if the error is a token rejected error.
+func IsTokenRejectedError(err error) bool {
+	return errors.Is(err, ErrTokenRejected)
+}
@@ -0,0 +1,359 @@
+package errors
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ProtocolError is returned when the service sends a message that does not follow
+// the protocol. It matches ErrUnknownResponse or ErrUnexpectedResponse.
+type ProtocolError struct {
+	// Err is ErrUnknownResponse or ErrUnexpectedResponse.
+	Err error
+
+	// Path is the Path header of the offending message, if known.
+	Path string
+
+	// Msg describes the problem.
+	Msg string
+}
+
+func (e *ProtocolError) Error() string {
+	return fmt.Sprintf("%v: %s", e.Err, e.Msg)
+}
+
+func (e *ProtocolError) Unwrap() error {
+	return e.Err
+}
+
+// Retryable reports false: the same request will most likely get the same response.
+func (e *ProtocolError) Retryable() bool {
+	return false
+}
+
+// HandshakeError is returned when the WebSocket connection to the service cannot be
+// established. It matches ErrWebSocketError.
+type HandshakeError struct {
+	// StatusCode is the HTTP status of the handshake response, or 0 if the
+	// service could not be reached.
+	StatusCode int
+
+	// Header holds the headers of the handshake response, if any.
+	Header http.Header
+
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *HandshakeError) Error() string {
+	if e.StatusCode == 0 {
+		return fmt.Sprintf("%v: %v", ErrWebSocketError, e.Err)
+	}
+	return fmt.Sprintf("%v: handshake failed with status %d %s: %v",
+		ErrWebSocketError, e.StatusCode, http.StatusText(e.StatusCode), e.Err)
+}
+
+func (e *HandshakeError) Unwrap() []error {
+	return []error{ErrWebSocketError, e.Err}
+}
+
+// Retryable reports whether the handshake may succeed later: when the service could
+// not be reached because of a network error, is rate limiting (429) or failed (5xx).
+// TLS verification failures and invalid URLs or proxies are not retryable.
+func (e *HandshakeError) Retryable() bool {
+	if e.StatusCode == 0 {
+		return isNetworkError(e.Err)
+	}
+	return isRetryableStatus(e.StatusCode)
+}
+
+// Throttled reports whether the service rejected the handshake because of rate limiting.
+func (e *HandshakeError) Throttled() bool {
+	return e.StatusCode == http.StatusTooManyRequests
+}
+
+// RetryAfter returns the delay requested by the Retry-After header of the response.
+func (e *HandshakeError) RetryAfter() (time.Duration, bool) {
+	return parseRetryAfter(e.Header)
+}
+
+// ResponseError is returned when an HTTP request to the service, such as the voice
+// list request, fails with an unexpected status. It matches ErrUnexpectedResponse.
+type ResponseError struct {
+	// StatusCode is the HTTP status of the response.
+	StatusCode int
+
+	// Header holds the headers of the response.
+	Header http.Header
+
+	// Msg describes the request that failed.
+	Msg string
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("%v: %s: %d %s", ErrUnexpectedResponse, e.Msg, e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+func (e *ResponseError) Unwrap() error {
+	return ErrUnexpectedResponse
+}
+
+// Retryable reports whether the request may succeed later: when the service is rate
+// limiting (429) or failed (5xx).
+func (e *ResponseError) Retryable() bool {
+	return isRetryableStatus(e.StatusCode)
+}
+
+// Throttled reports whether the service rejected the request because of rate limiting.
+func (e *ResponseError) Throttled() bool {
+	return e.StatusCode == http.StatusTooManyRequests
+}
+
+// RetryAfter returns the delay requested by the Retry-After header of the response.
+func (e *ResponseError) RetryAfter() (time.Duration, bool) {
+	return parseRetryAfter(e.Header)
+}
+
+// RequestError is returned when an HTTP request to the service, such as the voice
+// list request, gets no response, e.g. because the connection is refused. It
+// matches ErrEdgeTTS.
+type RequestError struct {
+	// Msg describes the request that failed.
+	Msg string
+
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *RequestError) Error() string {
+	return fmt.Sprintf("%v: %s: %v", ErrEdgeTTS, e.Msg, e.Err)
+}
+
+func (e *RequestError) Unwrap() []error {
+	return []error{ErrEdgeTTS, e.Err}
+}
+
+// Retryable reports whether the request failed because of a network error, like
+// HandshakeError.Retryable for a service that could not be reached.
+func (e *RequestError) Retryable() bool {
+	return isNetworkError(e.Err)
+}
+
+// CloseError is returned when the service closes the WebSocket connection with a
+// close frame before the end of the turn. It matches ErrWebSocketError.
+type CloseError struct {
+	// Code is the close code, as defined in RFC 6455, section 7.4.
+	Code int
+
+	// Reason is the close reason sent by the service.
+	Reason string
+}
+
+// WebSocket close codes sent by the service.
+const (
+	CloseNormalClosure   = 1000
+	CloseGoingAway       = 1001
+	CloseAbnormalClosure = 1006
+	ClosePolicyViolation = 1008
+	CloseInternalError   = 1011
+	CloseServiceRestart  = 1012
+	CloseTryAgainLater   = 1013
+)
+
+func (e *CloseError) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("%v: connection closed with code %d", ErrWebSocketError, e.Code)
+	}
+	return fmt.Sprintf("%v: connection closed with code %d: %s", ErrWebSocketError, e.Code, e.Reason)
+}
+
+func (e *CloseError) Unwrap() error {
+	return ErrWebSocketError
+}
+
+// Retryable reports whether the connection was closed because of a transient
+// condition on the service side, such as a restart or an overload.
+func (e *CloseError) Retryable() bool {
+	switch e.Code {
+	case CloseGoingAway, CloseAbnormalClosure, CloseInternalError, CloseServiceRestart, CloseTryAgainLater:
+		return true
+	default:
+		return false
+	}
+}
+
+// Throttled reports whether the service closed the connection because it is overloaded.
+func (e *CloseError) Throttled() bool {
+	return e.Code == CloseTryAgainLater
+}
+
+// TimeoutError is returned when the handshake or a receive does not complete in
+// time. It matches ErrTimeout.
+type TimeoutError struct {
+	// Op is the operation that timed out, "handshake" or "receive", if known.
+	Op string
+
+	// Limit is the timeout that elapsed, if known.
+	Limit time.Duration
+
+	// Msg describes the timeout.
+	Msg string
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("%v: %s", ErrTimeout, e.Msg)
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return ErrTimeout
+}
+
+// Timeout reports true, like net.Error.
+func (e *TimeoutError) Timeout() bool {
+	return true
+}
+
+// Retryable reports true.
+func (e *TimeoutError) Retryable() bool {
+	return true
+}
+
+// TokenRejectedError is returned when the Microsoft Edge endpoint rejects the
+// Sec-MS-GEC token while the corrected clock is in sync with the service, so that
+// adjusting the clock skew cannot help. It matches ErrTokenRejected.
+type TokenRejectedError struct {
+	// Skew is the clock skew in use when the token was rejected.
+	Skew time.Duration
+
+	// Version is the Sec-MS-GEC-Version sent with the token, if known.
+	Version string
+}
+
+func (e *TokenRejectedError) Error() string {
+	version := "Sec-MS-GEC-Version"
+	if e.Version != "" {
+		version = fmt.Sprintf("Sec-MS-GEC-Version %q", e.Version)
+	}
+	return fmt.Sprintf("%v: the clock is in sync with the service (skew %s), so the %s is "+
+		"probably outdated: update the SecMSGECVersion by setting endpoint.Endpoint.ClientVersion "+
+		"to a current Microsoft Edge version", ErrTokenRejected, e.Skew, version)
+}
+
+func (e *TokenRejectedError) Unwrap() error {
+	return ErrTokenRejected
+}
+
+// Retryable reports false: the token will be rejected until the version is updated.
+func (e *TokenRejectedError) Retryable() bool {
+	return false
+}
+
+// SynthesisError adds the voice and the index of the text chunk to an error that
+// occurred while synthesizing that chunk.
+type SynthesisError struct {
+	// Chunk is the index of the text chunk.
+	Chunk int
+
+	// Voice is the voice of the request.
+	Voice string
+
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *SynthesisError) Error() string {
+	return fmt.Sprintf("chunk %d with voice %s: %v", e.Chunk, e.Voice, e.Err)
+}
+
+func (e *SynthesisError) Unwrap() error {
+	return e.Err
+}
+
+// IsRetryable reports whether retrying the request that returned err may succeed.
+//
+// Errors with a Retryable method, such as *HandshakeError and *TimeoutError, decide
+// for themselves. Otherwise, WebSocket errors (e.g. a dropped connection) and
+// timeouts are retryable, while protocol errors, missing audio, clock skew errors
+// and context errors are not.
+func IsRetryable(err error) bool {
+	var r interface{ Retryable() bool }
+	if errors.As(err, &r) {
+		return r.Retryable()
+	}
+	return IsWebSocketError(err) || IsTimeoutError(err)
+}
+
+// IsThrottled reports whether err was caused by the service rate limiting the
+// client, with a 429 status or a "try again later" close code.
+func IsThrottled(err error) bool {
+	var t interface{ Throttled() bool }
+	return errors.As(err, &t) && t.Throttled()
+}
+
+// RetryAfter returns the delay the service asked to wait before retrying, from the
+// Retry-After header of the response that caused err.
+func RetryAfter(err error) (time.Duration, bool) {
+	var r interface {
+		RetryAfter() (time.Duration, bool)
+	}
+	if errors.As(err, &r) {
+		return r.RetryAfter()
+	}
+	return 0, false
+}
+
+// parseRetryAfter parses a Retry-After header, holding either a number of seconds
+// or an HTTP date.
+func parseRetryAfter(header http.Header) (time.Duration, bool) {
+	value := strings.TrimSpace(header.Get("Retry-After"))
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		d := time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+// isRetryableStatus reports whether a request with the given HTTP status may succeed later.
+func isRetryableStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+}
+
+// isNetworkError reports whether err is a network error, such as a refused or reset
+// connection, that may not occur again. Certificate and TLS handshake failures are
+// not, nor are errors that occur before dialing, such as an invalid URL or proxy.
+func isNetworkError(err error) bool {
+	var certErr *tls.CertificateVerificationError
+	var alertErr tls.AlertError
+	var recordErr tls.RecordHeaderError
+	var authorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	if errors.As(err, &certErr) || errors.As(err, &alertErr) || errors.As(err, &recordErr) ||
+		errors.As(err, &authorityErr) || errors.As(err, &hostnameErr) || errors.As(err, &invalidErr) {
+		return false
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return !dnsErr.IsNotFound
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
@@ -0,0 +1,83 @@
+package errors
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestIsRetryable(t *testing.T) {
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"connection refused", &HandshakeError{Err: refused}, true},
+		{"connection reset", &HandshakeError{Err: &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}}, true},
+		{"unexpected EOF", &HandshakeError{Err: io.ErrUnexpectedEOF}, true},
+		{"temporary DNS failure", &HandshakeError{Err: &net.DNSError{Err: "server misbehaving", IsTemporary: true}}, true},
+		{"unknown host", &HandshakeError{Err: &net.DNSError{Err: "no such host", IsNotFound: true}}, false},
+		{"unknown authority", &HandshakeError{Err: &tls.CertificateVerificationError{Err: x509.UnknownAuthorityError{}}}, false},
+		{"hostname mismatch", &HandshakeError{Err: x509.HostnameError{Host: "example.com"}}, false},
+		{"TLS alert", &HandshakeError{Err: &net.OpError{Op: "remote error", Err: tls.AlertError(40)}}, false},
+		{"not TLS", &HandshakeError{Err: tls.RecordHeaderError{Msg: "first record does not look like a TLS handshake"}}, false},
+		{"invalid URL", &HandshakeError{Err: &url.Error{Op: "parse", URL: "::", Err: errors.New("missing protocol scheme")}}, false},
+		{"invalid proxy", &HandshakeError{Err: errors.New(`unsupported proxy scheme "ftp"`)}, false},
+		{"throttled", &HandshakeError{StatusCode: http.StatusTooManyRequests}, true},
+		{"unavailable", &HandshakeError{StatusCode: http.StatusServiceUnavailable}, true},
+		{"forbidden", &HandshakeError{StatusCode: http.StatusForbidden}, false},
+		{"server error response", &ResponseError{StatusCode: http.StatusBadGateway}, true},
+		{"not found response", &ResponseError{StatusCode: http.StatusNotFound}, false},
+		{"request refused", &RequestError{Err: &url.Error{Op: "Get", URL: "http://127.0.0.1:1", Err: refused}}, true},
+		{"request unknown host", &RequestError{Err: &url.Error{Op: "Get", URL: "http://invalid", Err: &net.DNSError{IsNotFound: true}}}, false},
+		{"going away", &CloseError{Code: CloseGoingAway}, true},
+		{"policy violation", &CloseError{Code: ClosePolicyViolation}, false},
+		{"timeout", &TimeoutError{Op: "receive"}, true},
+		{"protocol", &ProtocolError{Err: ErrUnknownResponse}, false},
+		{"token rejected", &TokenRejectedError{}, false},
+		{"wrapped", &SynthesisError{Err: &HandshakeError{Err: refused}}, true},
+		{"websocket", NewWebSocketError("connection dropped"), true},
+		{"no audio", NewNoAudioReceivedError("no audio"), false},
+		{"other", fmt.Errorf("other"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRetryable(tt.err); got != tt.want {
+				t.Errorf("IsRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		name   string
+		header http.Header
+		want   time.Duration
+		wantOK bool
+	}{
+		{"seconds", http.Header{"Retry-After": {"30"}}, 30 * time.Second, true},
+		{"past date", http.Header{"Retry-After": {"Mon, 01 Jan 2001 00:00:00 GMT"}}, 0, true},
+		{"invalid", http.Header{"Retry-After": {"soon"}}, 0, false},
+		{"missing", http.Header{}, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := &SynthesisError{Err: &HandshakeError{StatusCode: http.StatusTooManyRequests, Header: tt.header}}
+			got, ok := RetryAfter(err)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("RetryAfter() = %s, %v, want %s, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+// Package metrics defines the collector of the synthesis metrics, with an
+// implementation that exposes them in the Prometheus text format.
+package metrics
+
+import (
+	"context"
+	stderrors "errors"
+	"time"
+
+	"github.com/difyz9/edge-tts-go/pkg/errors"
+)
+
+// OutcomeOK is the outcome of a successful request.
+const OutcomeOK = "ok"
+
+// Collector receives the metrics of the synthesis. Its methods may be called
+// concurrently.
+type Collector interface {
+	// ObserveRequest records a finished synthesis request with the voice and its
+	// outcome: OutcomeOK or the ErrorCategory of the error that ended it.
+	ObserveRequest(voice, outcome string)
+
+	// ObserveHandshake records the time taken to connect to the service.
+	ObserveHandshake(d time.Duration)
+
+	// ObserveFirstAudio records the time between sending an SSML request and
+	// receiving its first audio data.
+	ObserveFirstAudio(d time.Duration)
+
+	// AddAudio records audio received from the service, in bytes and playback duration.
+	AddAudio(bytes int, d time.Duration)
+
+	// ObserveSkewCorrection records a clock skew correction, in seconds.
+	ObserveSkewCorrection(seconds float64)
+
+	// IncError records an error with its ErrorCategory.
+	IncError(category string)
+}
+
+// Nop is a Collector that discards every metric.
+type Nop struct{}
+
+func (Nop) ObserveRequest(voice, outcome string)  {}
+func (Nop) ObserveHandshake(d time.Duration)      {}
+func (Nop) ObserveFirstAudio(d time.Duration)     {}
+func (Nop) AddAudio(bytes int, d time.Duration)   {}
+func (Nop) ObserveSkewCorrection(seconds float64) {}
+func (Nop) IncError(category string)              {}
+
+// Or returns c, or Nop if c is nil.
+func Or(c Collector) Collector {
+	if c == nil {
+		return Nop{}
+	}
+	return c
+}
+
+// ErrorCategory returns the category of err: "throttled" when the service is rate
+// limiting, or else the name of the pkg/errors sentinel it wraps: "unknown_response",
+// "unexpected_response", "no_audio_received", "websocket", "skew_adjustment",
+// "token_rejected" or "timeout". Context errors are "canceled" and any other error is "other".
+func ErrorCategory(err error) string {
+	switch {
+	case errors.IsThrottled(err):
+		return "throttled"
+	case errors.IsUnknownResponseError(err):
+		return "unknown_response"
+	case errors.IsUnexpectedResponseError(err):
+		return "unexpected_response"
+	case errors.IsNoAudioReceivedError(err):
+		return "no_audio_received"
+	case errors.IsWebSocketError(err):
+		return "websocket"
+	case errors.IsSkewAdjustmentError(err):
+		return "skew_adjustment"
+	case errors.IsTokenRejectedError(err):
+		return "token_rejected"
+	case errors.IsTimeoutError(err):
+		return "timeout"
+	case stderrors.Is(err, context.Canceled), stderrors.Is(err, context.DeadlineExceeded):
+		return "canceled"
+	default:
+		return "other"
+	}
+}
@@ -0,0 +1,195 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultBuckets are the upper bounds, in seconds, of the latency histograms.
+var DefaultBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Prometheus is a Collector that exposes the metrics in the Prometheus text
+// exposition format. It is an http.Handler, so it can be mounted on /metrics:
+//
+//	m := metrics.NewPrometheus()
+//	comm.SetMetrics(m)
+//	http.Handle("/metrics", m)
+type Prometheus struct {
+	mu              sync.Mutex
+	requests        map[[2]string]float64
+	errors          map[string]float64
+	handshake       *histogram
+	firstAudio      *histogram
+	audioBytes      float64
+	audioSeconds    float64
+	skewCorrections float64
+	skewSeconds     float64
+}
+
+// NewPrometheus creates a new Prometheus collector.
+func NewPrometheus() *Prometheus {
+	return &Prometheus{
+		requests:   make(map[[2]string]float64),
+		errors:     make(map[string]float64),
+		handshake:  newHistogram(DefaultBuckets),
+		firstAudio: newHistogram(DefaultBuckets),
+	}
+}
+
+// ObserveRequest implements Collector.
+func (p *Prometheus) ObserveRequest(voice, outcome string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.requests[[2]string{voice, outcome}]++
+}
+
+// ObserveHandshake implements Collector.
+func (p *Prometheus) ObserveHandshake(d time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.handshake.observe(d.Seconds())
+}
+
+// ObserveFirstAudio implements Collector.
+func (p *Prometheus) ObserveFirstAudio(d time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.firstAudio.observe(d.Seconds())
+}
+
+// AddAudio implements Collector.
+func (p *Prometheus) AddAudio(bytes int, d time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.audioBytes += float64(bytes)
+	p.audioSeconds += d.Seconds()
+}
+
+// ObserveSkewCorrection implements Collector.
+func (p *Prometheus) ObserveSkewCorrection(seconds float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.skewCorrections++
+	p.skewSeconds = seconds
+}
+
+// IncError implements Collector.
+func (p *Prometheus) IncError(category string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.errors[category]++
+}
+
+// WriteTo writes the metrics to w in the Prometheus text exposition format.
+func (p *Prometheus) WriteTo(w io.Writer) (int64, error) {
+	p.mu.Lock()
+	var buf bytes.Buffer
+
+	writeHeader(&buf, "edge_tts_requests_total", "counter", "Synthesis requests by voice and outcome.")
+	keys := make([][2]string, 0, len(p.requests))
+	for k := range p.requests {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i][0] != keys[j][0] {
+			return keys[i][0] < keys[j][0]
+		}
+		return keys[i][1] < keys[j][1]
+	})
+	for _, k := range keys {
+		fmt.Fprintf(&buf, "edge_tts_requests_total{voice=\"%s\",outcome=\"%s\"} %s\n",
+			escapeLabel(k[0]), escapeLabel(k[1]), formatValue(p.requests[k]))
+	}
+
+	writeHeader(&buf, "edge_tts_errors_total", "counter", "Errors by category.")
+	categories := make([]string, 0, len(p.errors))
+	for k := range p.errors {
+		categories = append(categories, k)
+	}
+	sort.Strings(categories)
+	for _, k := range categories {
+		fmt.Fprintf(&buf, "edge_tts_errors_total{category=\"%s\"} %s\n", escapeLabel(k), formatValue(p.errors[k]))
+	}
+
+	writeHeader(&buf, "edge_tts_handshake_seconds", "histogram", "Time taken to connect to the service.")
+	p.handshake.write(&buf, "edge_tts_handshake_seconds")
+
+	writeHeader(&buf, "edge_tts_time_to_first_audio_seconds", "histogram", "Time between an SSML request and its first audio data.")
+	p.firstAudio.write(&buf, "edge_tts_time_to_first_audio_seconds")
+
+	writeHeader(&buf, "edge_tts_audio_bytes_total", "counter", "Bytes of audio received.")
+	fmt.Fprintf(&buf, "edge_tts_audio_bytes_total %s\n", formatValue(p.audioBytes))
+
+	writeHeader(&buf, "edge_tts_audio_seconds_total", "counter", "Seconds of audio received.")
+	fmt.Fprintf(&buf, "edge_tts_audio_seconds_total %s\n", formatValue(p.audioSeconds))
+
+	writeHeader(&buf, "edge_tts_clock_skew_corrections_total", "counter", "Clock skew corrections.")
+	fmt.Fprintf(&buf, "edge_tts_clock_skew_corrections_total %s\n", formatValue(p.skewCorrections))
+
+	writeHeader(&buf, "edge_tts_clock_skew_adjustment_seconds", "gauge", "Last clock skew adjustment.")
+	fmt.Fprintf(&buf, "edge_tts_clock_skew_adjustment_seconds %s\n", formatValue(p.skewSeconds))
+
+	p.mu.Unlock()
+	return buf.WriteTo(w)
+}
+
+// ServeHTTP writes the metrics in the Prometheus text exposition format.
+func (p *Prometheus) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	p.WriteTo(w)
+}
+
+// histogram is a cumulative histogram with fixed buckets.
+type histogram struct {
+	bounds []float64
+	counts []float64
+	sum    float64
+	count  float64
+}
+
+func newHistogram(bounds []float64) *histogram {
+	return &histogram{bounds: bounds, counts: make([]float64, len(bounds))}
+}
+
+func (h *histogram) observe(v float64) {
+	for i, bound := range h.bounds {
+		if v <= bound {
+			h.counts[i]++
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+func (h *histogram) write(buf *bytes.Buffer, name string) {
+	for i, bound := range h.bounds {
+		fmt.Fprintf(buf, "%s_bucket{le=\"%s\"} %s\n", name, formatValue(bound), formatValue(h.counts[i]))
+	}
+	fmt.Fprintf(buf, "%s_bucket{le=\"+Inf\"} %s\n", name, formatValue(h.count))
+	fmt.Fprintf(buf, "%s_sum %s\n", name, formatValue(h.sum))
+	fmt.Fprintf(buf, "%s_count %s\n", name, formatValue(h.count))
+}
+
+// writeHeader writes the HELP and TYPE lines of a metric.
+func writeHeader(buf *bytes.Buffer, name, typ, help string) {
+	fmt.Fprintf(buf, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+// formatValue formats a sample value.
+func formatValue(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// labelEscaper escapes label values as required by the text exposition format.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
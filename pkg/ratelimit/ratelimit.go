@@ -0,0 +1,200 @@
+// Package ratelimit provides a client-side limiter of the requests sent to the
+// service, to be shared by every Communicate and voice list request of a process.
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/difyz9/edge-tts-go/pkg/errors"
+)
+
+// DefaultBackoff is the pause applied by Report to a throttling error that does not
+// say how long to wait.
+const DefaultBackoff = 30 * time.Second
+
+// Config represents the limits of a Limiter. Zero values mean no limit.
+type Config struct {
+	// RequestsPerMinute is the number of connections opened per minute.
+	RequestsPerMinute int
+
+	// CharactersPerMinute is the number of characters of text sent per minute.
+	CharactersPerMinute int
+
+	// MaxConcurrent is the number of connections open at the same time.
+	MaxConcurrent int
+}
+
+// Limiter limits the rate of requests and characters with token buckets, which
+// allow bursts of up to a minute worth of tokens, and the number of concurrent
+// connections. A nil *Limiter does not limit anything.
+type Limiter struct {
+	// now reads the time of the buckets and pauses, time.Now outside of tests.
+	now func() time.Time
+
+	mu          sync.Mutex
+	requests    *bucket
+	characters  *bucket
+	slots       chan struct{}
+	pausedUntil time.Time
+}
+
+// NewLimiter creates a new Limiter.
+func NewLimiter(cfg Config) *Limiter {
+	l := &Limiter{
+		now:        time.Now,
+		requests:   newBucket(cfg.RequestsPerMinute),
+		characters: newBucket(cfg.CharactersPerMinute),
+	}
+	if cfg.MaxConcurrent > 0 {
+		l.slots = make(chan struct{}, cfg.MaxConcurrent)
+	}
+	return l
+}
+
+// Acquire blocks until a request sending the given number of characters is allowed,
+// or until ctx is done. The returned function must be called when the request is
+// finished, to free its connection slot.
+func (l *Limiter) Acquire(ctx context.Context, characters int) (release func(), err error) {
+	if l == nil {
+		return func() {}, nil
+	}
+
+	// Wait for a connection slot
+	if l.slots != nil {
+		select {
+		case l.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	release = func() {}
+	if l.slots != nil {
+		var once sync.Once
+		release = func() {
+			once.Do(func() { <-l.slots })
+		}
+	}
+
+	// Wait for the tokens
+	for {
+		wait := l.take(characters)
+		if wait == 0 {
+			return release, nil
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			release()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// take takes the tokens of a request if they are available, or else returns how
+// long to wait before trying again.
+func (l *Limiter) take(characters int) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if now.Before(l.pausedUntil) {
+		return l.pausedUntil.Sub(now)
+	}
+
+	wait := l.requests.wait(now, 1)
+	if w := l.characters.wait(now, characters); w > wait {
+		wait = w
+	}
+	if wait > 0 {
+		return wait
+	}
+
+	l.requests.take(1)
+	l.characters.take(characters)
+	return 0
+}
+
+// Backoff pauses every acquisition for d, for example when the service asks to
+// slow down.
+func (l *Limiter) Backoff(d time.Duration) {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if until := l.now().Add(d); until.After(l.pausedUntil) {
+		l.pausedUntil = until
+	}
+}
+
+// Report backs off when err shows that the service is throttling, for the delay
+// of its Retry-After header or DefaultBackoff. It reports whether it backed off.
+func (l *Limiter) Report(err error) bool {
+	if l == nil || err == nil {
+		return false
+	}
+
+	wait, ok := errors.RetryAfter(err)
+	if !ok {
+		if !errors.IsThrottled(err) {
+			return false
+		}
+		wait = DefaultBackoff
+	}
+	l.Backoff(wait)
+	return true
+}
+
+// bucket is a token bucket refilled at perMinute tokens per minute, holding at
+// most perMinute tokens. A nil bucket has unlimited tokens.
+type bucket struct {
+	perMinute float64
+	tokens    float64
+	updated   time.Time
+}
+
+func newBucket(perMinute int) *bucket {
+	if perMinute <= 0 {
+		return nil
+	}
+	return &bucket{perMinute: float64(perMinute), tokens: float64(perMinute)}
+}
+
+// wait refills the bucket and returns how long to wait until n tokens are available.
+// Requests for more tokens than the bucket holds wait for a full bucket.
+func (b *bucket) wait(now time.Time, n int) time.Duration {
+	if b == nil {
+		return 0
+	}
+
+	if !b.updated.IsZero() {
+		b.tokens += now.Sub(b.updated).Minutes() * b.perMinute
+		if b.tokens > b.perMinute {
+			b.tokens = b.perMinute
+		}
+	}
+	b.updated = now
+
+	need := float64(n)
+	if need > b.perMinute {
+		need = b.perMinute
+	}
+	if b.tokens >= need {
+		return 0
+	}
+	return time.Duration((need - b.tokens) / b.perMinute * float64(time.Minute))
+}
+
+// take removes n tokens, leaving a debt for requests larger than the bucket.
+func (b *bucket) take(n int) {
+	if b == nil {
+		return
+	}
+	b.tokens -= float64(n)
+}
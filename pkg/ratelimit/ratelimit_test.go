@@ -0,0 +1,205 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/difyz9/edge-tts-go/pkg/errors"
+)
+
+// fakeClock is a clock that only moves when advanced.
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	return c.t
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.t = c.t.Add(d)
+}
+
+// newTestLimiter creates a Limiter reading the time from a new fake clock.
+func newTestLimiter(cfg Config) (*Limiter, *fakeClock) {
+	clock := &fakeClock{t: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	l := NewLimiter(cfg)
+	l.now = clock.now
+	return l, clock
+}
+
+// step is a call to take after advancing the clock, with the wait it must return.
+type step struct {
+	advance    time.Duration
+	characters int
+	want       time.Duration
+}
+
+// checkSteps runs the steps against l.
+func checkSteps(t *testing.T, l *Limiter, clock *fakeClock, steps []step) {
+	t.Helper()
+	for i, s := range steps {
+		clock.advance(s.advance)
+		if got := l.take(s.characters); got != s.want {
+			t.Errorf("step %d: take(%d) after %s = %s, want %s", i, s.characters, s.advance, got, s.want)
+		}
+	}
+}
+
+func TestBucketRefill(t *testing.T) {
+	tests := []struct {
+		name  string
+		cfg   Config
+		steps []step
+	}{
+		{
+			name: "requests",
+			cfg:  Config{RequestsPerMinute: 2},
+			steps: []step{
+				{0, 0, 0},
+				{0, 0, 0},
+				{0, 0, 30 * time.Second},
+				{10 * time.Second, 0, 20 * time.Second},
+				{20 * time.Second, 0, 0},
+				{0, 0, 30 * time.Second},
+			},
+		},
+		{
+			name: "characters",
+			cfg:  Config{CharactersPerMinute: 60},
+			steps: []step{
+				{0, 60, 0},
+				{0, 30, 30 * time.Second},
+				{15 * time.Second, 30, 15 * time.Second},
+				{15 * time.Second, 30, 0},
+			},
+		},
+		{
+			name: "full bucket after a minute",
+			cfg:  Config{CharactersPerMinute: 60},
+			steps: []step{
+				{0, 60, 0},
+				{time.Hour, 60, 0},
+				{0, 1, time.Second},
+			},
+		},
+		{
+			name: "larger than the bucket",
+			cfg:  Config{CharactersPerMinute: 60},
+			steps: []step{
+				{0, 120, 0},
+				// The debt of 60 characters must be refilled first
+				{0, 1, 61 * time.Second},
+				{61 * time.Second, 1, 0},
+			},
+		},
+		{
+			name: "no limits",
+			cfg:  Config{},
+			steps: []step{
+				{0, 1000000, 0},
+				{0, 1000000, 0},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, clock := newTestLimiter(tt.cfg)
+			checkSteps(t, l, clock, tt.steps)
+		})
+	}
+}
+
+func TestReport(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want time.Duration
+	}{
+		{
+			name: "Retry-After",
+			err: &errors.SynthesisError{Err: &errors.HandshakeError{
+				StatusCode: http.StatusTooManyRequests,
+				Header:     http.Header{"Retry-After": {"10"}},
+			}},
+			want: 10 * time.Second,
+		},
+		{
+			name: "throttled without Retry-After",
+			err:  &errors.CloseError{Code: errors.CloseTryAgainLater},
+			want: DefaultBackoff,
+		},
+		{
+			name: "not throttled",
+			err:  errors.NewNoAudioReceivedError("no audio"),
+		},
+		{
+			name: "no error",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, clock := newTestLimiter(Config{})
+			if got := l.Report(tt.err); got != (tt.want > 0) {
+				t.Errorf("Report(%v) = %v, want %v", tt.err, got, tt.want > 0)
+			}
+			checkSteps(t, l, clock, []step{
+				{0, 0, tt.want},
+				{tt.want / 2, 0, tt.want - tt.want/2},
+				{tt.want - tt.want/2, 0, 0},
+			})
+		})
+	}
+}
+
+func TestBackoffKeepsLongestPause(t *testing.T) {
+	l, clock := newTestLimiter(Config{})
+	l.Backoff(time.Minute)
+	l.Backoff(time.Second)
+	checkSteps(t, l, clock, []step{
+		{0, 0, time.Minute},
+		{30 * time.Second, 0, 30 * time.Second},
+	})
+}
+
+func TestAcquireCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	l, _ := newTestLimiter(Config{RequestsPerMinute: 1, MaxConcurrent: 1})
+	release, err := l.Acquire(context.Background(), 0)
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+
+	// The only slot is taken
+	if _, err := l.Acquire(ctx, 0); err != context.Canceled {
+		t.Errorf("Acquire with a taken slot = %v, want context.Canceled", err)
+	}
+	release()
+	release()
+
+	// The slot is free, but the bucket is empty: the slot is freed again on cancel
+	if _, err := l.Acquire(ctx, 0); err != context.Canceled {
+		t.Errorf("Acquire with an empty bucket = %v, want context.Canceled", err)
+	}
+	if len(l.slots) != 0 {
+		t.Errorf("slots taken = %d after a canceled Acquire, want 0", len(l.slots))
+	}
+}
+
+func TestNilLimiter(t *testing.T) {
+	var l *Limiter
+	release, err := l.Acquire(context.Background(), 1000)
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	release()
+
+	l.Backoff(time.Minute)
+	if l.Report(&errors.CloseError{Code: errors.CloseTryAgainLater}) {
+		t.Error("Report on a nil Limiter = true, want false")
+	}
+}
@@ -0,0 +1,248 @@
+// Package record captures the WebSocket frames exchanged with the TTS service, so
+// that they can be inspected or served back by ttstest.ReplayServer.
+//
+// Sessions are stored as JSON lines, one Frame per line.
+package record
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/difyz9/edge-tts-go/pkg/util"
+)
+
+// Message types, as defined by RFC 6455.
+const (
+	TextMessage   = 1
+	BinaryMessage = 2
+)
+
+// Frame directions.
+const (
+	Sent     = "sent"
+	Received = "received"
+)
+
+// Frame is a single WebSocket message exchanged with the service.
+type Frame struct {
+	Connection int               `json:"connection"`
+	Direction  string            `json:"direction"`
+	Type       string            `json:"type"` // "text" or "binary"
+	Time       time.Time         `json:"time"`
+	Headers    map[string]string `json:"headers"`
+	Text       string            `json:"text,omitempty"` // body of a text frame
+	Body       []byte            `json:"body,omitempty"` // body of a binary frame
+}
+
+// NewFrame creates a frame from a raw WebSocket message.
+func NewFrame(connection int, direction string, messageType int, data []byte) Frame {
+	f := Frame{
+		Connection: connection,
+		Direction:  direction,
+		Type:       "text",
+		Time:       time.Now().UTC(),
+	}
+
+	if messageType == BinaryMessage {
+		f.Type = "binary"
+		if len(data) >= 2 {
+			// The first two bytes hold the length of the header block
+			headerLength := int(binary.BigEndian.Uint16(data[:2]))
+			if headerLength+2 <= len(data) {
+				headerBlock := append([]byte{}, data[2:2+headerLength]...)
+				f.Headers, _ = util.ProcessWebsocketMessage(append(headerBlock, "\r\n\r\n"...))
+				f.Body = append([]byte{}, data[2+headerLength:]...)
+				return f
+			}
+		}
+		f.Headers = map[string]string{}
+		f.Body = append([]byte{}, data...)
+		return f
+	}
+
+	headers, body := util.ProcessWebsocketMessage(data)
+	f.Headers = headers
+	f.Text = string(body)
+	return f
+}
+
+// MessageType returns the WebSocket message type of the frame.
+func (f Frame) MessageType() int {
+	if f.Type == "binary" {
+		return BinaryMessage
+	}
+	return TextMessage
+}
+
+// Data rebuilds the raw WebSocket message of the frame. Headers are written in
+// alphabetical order, with Path last.
+func (f Frame) Data() []byte {
+	keys := make([]string, 0, len(f.Headers))
+	for k := range f.Headers {
+		if k != "Path" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	if _, ok := f.Headers["Path"]; ok {
+		keys = append(keys, "Path")
+	}
+
+	var headerBlock []byte
+	for _, k := range keys {
+		headerBlock = append(headerBlock, k+":"+f.Headers[k]+"\r\n"...)
+	}
+
+	if f.MessageType() == BinaryMessage {
+		data := make([]byte, 2, 2+len(headerBlock)+len(f.Body))
+		binary.BigEndian.PutUint16(data, uint16(len(headerBlock)))
+		data = append(data, headerBlock...)
+		return append(data, f.Body...)
+	}
+
+	data := append(headerBlock, "\r\n"...)
+	return append(data, f.Text...)
+}
+
+// Recorder writes the frames of one or more connections to a writer.
+type Recorder struct {
+	mu          sync.Mutex
+	enc         *json.Encoder
+	closer      io.Closer
+	connections int
+	err         error
+}
+
+// NewRecorder creates a new Recorder writing to w.
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{enc: json.NewEncoder(w)}
+}
+
+// Create creates a new Recorder writing to the named file.
+func Create(name string) (*Recorder, error) {
+	f, err := os.Create(name)
+	if err != nil {
+		return nil, err
+	}
+	r := NewRecorder(f)
+	r.closer = f
+	return r, nil
+}
+
+// Conn starts recording a new connection.
+func (r *Recorder) Conn() *Conn {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.connections++
+	return &Conn{recorder: r, id: r.connections}
+}
+
+// Err returns the first error encountered while writing frames.
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// Close closes the underlying file if the Recorder was created by Create.
+// It returns the first error encountered while writing frames, if any.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closer != nil {
+		if err := r.closer.Close(); err != nil && r.err == nil {
+			r.err = err
+		}
+		r.closer = nil
+	}
+	return r.err
+}
+
+// write writes a frame.
+func (r *Recorder) write(f Frame) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return
+	}
+	r.err = r.enc.Encode(f)
+}
+
+// Conn records the frames of a single connection.
+type Conn struct {
+	recorder *Recorder
+	id       int
+}
+
+// Sent records a message sent to the service.
+func (c *Conn) Sent(messageType int, data []byte) {
+	c.recorder.write(NewFrame(c.id, Sent, messageType, data))
+}
+
+// Received records a message received from the service.
+func (c *Conn) Received(messageType int, data []byte) {
+	c.recorder.write(NewFrame(c.id, Received, messageType, data))
+}
+
+// Session is a recorded session.
+type Session struct {
+	Frames []Frame
+}
+
+// ReadSession reads a session written by a Recorder.
+func ReadSession(r io.Reader) (*Session, error) {
+	s := &Session{}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var f Frame
+		if err := json.Unmarshal(scanner.Bytes(), &f); err != nil {
+			return nil, fmt.Errorf("invalid frame on line %d: %w", line, err)
+		}
+		s.Frames = append(s.Frames, f)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Load reads a session from the named file.
+func Load(name string) (*Session, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadSession(f)
+}
+
+// Connections returns the frames of each connection, in the order the connections were made.
+func (s *Session) Connections() [][]Frame {
+	var ids []int
+	frames := map[int][]Frame{}
+	for _, f := range s.Frames {
+		if _, ok := frames[f.Connection]; !ok {
+			ids = append(ids, f.Connection)
+		}
+		frames[f.Connection] = append(frames[f.Connection], f)
+	}
+
+	connections := make([][]Frame, 0, len(ids))
+	for _, id := range ids {
+		connections = append(connections, frames[id])
+	}
+	return connections
+}
@@ -0,0 +1,112 @@
+package submaker
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/difyz9/edge-tts-go/pkg/util"
+)
+
+// GetLRC returns the LRC (lyrics) formatted subtitles from the SubMaker.
+//
+// Each cue becomes a "[mm:ss.xx]" line. If enhanced is true, every word fed to the
+// SubMaker is also tagged with its own "<mm:ss.xx>" start time, which allows music
+// players to highlight words as they are spoken.
+func (sm *SubMaker) GetLRC(enhanced bool) string {
+	var sb strings.Builder
+
+	word := 0
+	for i, cue := range sm.cues {
+		sb.WriteString(fmt.Sprintf("[%s]", formatLRCTimestamp(cue.Start)))
+
+		// Collect the words spoken during this cue
+		var tagged []string
+		for enhanced && word < len(sm.words) && sm.words[word].Start < cue.End {
+			if sm.words[word].Start >= cue.Start {
+				tagged = append(tagged, fmt.Sprintf("<%s>%s",
+					formatLRCTimestamp(sm.words[word].Start), lrcLine(sm.words[word].Content)))
+			}
+			word++
+		}
+
+		if len(tagged) > 0 {
+			sb.WriteString(strings.Join(tagged, " "))
+		} else {
+			sb.WriteString(lrcLine(cue.Content))
+		}
+		sb.WriteString("\n")
+
+		// Clear the line when there is a gap before the next cue
+		if i == len(sm.cues)-1 || sm.cues[i+1].Start > cue.End {
+			sb.WriteString(fmt.Sprintf("[%s]\n", formatLRCTimestamp(cue.End)))
+		}
+	}
+
+	return sb.String()
+}
+
+// GetTTML returns the TTML formatted subtitles from the SubMaker, conforming to
+// the IMSC1 text profile. lang is the xml:lang of the document, e.g. "en-US".
+func (sm *SubMaker) GetTTML(lang string) string {
+	if lang == "" {
+		lang = "en"
+	}
+
+	var sb strings.Builder
+
+	sb.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	sb.WriteString(fmt.Sprintf("<tt xmlns=\"http://www.w3.org/ns/ttml\""+
+		" xmlns:ttp=\"http://www.w3.org/ns/ttml#parameter\""+
+		" xmlns:tts=\"http://www.w3.org/ns/ttml#styling\""+
+		" ttp:profile=\"http://www.w3.org/ns/ttml/profile/imsc1/text\""+
+		" ttp:timeBase=\"media\" xml:lang=\"%s\">\n", util.EscapeXML(lang)))
+	sb.WriteString("  <head>\n")
+	sb.WriteString("    <layout>\n")
+	sb.WriteString("      <region xml:id=\"bottom\" tts:origin=\"10% 80%\" tts:extent=\"80% 15%\"" +
+		" tts:displayAlign=\"after\" tts:textAlign=\"center\"/>\n")
+	sb.WriteString("    </layout>\n")
+	sb.WriteString("  </head>\n")
+	sb.WriteString("  <body region=\"bottom\">\n")
+	sb.WriteString("    <div>\n")
+
+	for _, cue := range sm.cues {
+		lines := strings.Split(cue.Content, "\n")
+		for i := range lines {
+			lines[i] = util.EscapeXML(lines[i])
+		}
+
+		sb.WriteString(fmt.Sprintf("      <p xml:id=\"c%d\" begin=\"%s\" end=\"%s\">%s</p>\n",
+			cue.Index, formatTTMLTimestamp(cue.Start), formatTTMLTimestamp(cue.End),
+			strings.Join(lines, "<br/>")))
+	}
+
+	sb.WriteString("    </div>\n")
+	sb.WriteString("  </body>\n")
+	sb.WriteString("</tt>\n")
+
+	return sb.String()
+}
+
+// formatLRCTimestamp formats a duration as "mm:ss.xx", rounded to the nearest
+// hundredth of a second. Minutes are not wrapped into hours, as LRC has no hours.
+func formatLRCTimestamp(d time.Duration) string {
+	d = d.Round(10 * time.Millisecond)
+	m := d / time.Minute
+	d -= m * time.Minute
+	s := d / time.Second
+	d -= s * time.Second
+	cs := d / (10 * time.Millisecond)
+
+	return fmt.Sprintf("%02d:%02d.%02d", m, s, cs)
+}
+
+// formatTTMLTimestamp formats a duration as "00:00:00.000".
+func formatTTMLTimestamp(d time.Duration) string {
+	return strings.Replace(formatDuration(d), ",", ".", 1)
+}
+
+// lrcLine flattens multi-line content, as LRC has one line per timestamp.
+func lrcLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
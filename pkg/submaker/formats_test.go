@@ -0,0 +1,113 @@
+package submaker
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/difyz9/edge-tts-go/pkg/types"
+)
+
+var update = flag.Bool("update", false, "write the golden subtitles in testdata again")
+
+// newGoldenSubMaker returns a SubMaker with words that need escaping and timestamps
+// that need rounding, past the first hour, merged two words per cue.
+func newGoldenSubMaker(t *testing.T) *SubMaker {
+	t.Helper()
+	words := []struct {
+		text            string
+		start, duration time.Duration
+	}{
+		{"Fish", 0, 1234 * time.Millisecond},
+		{"&", 1996 * time.Millisecond, 500 * time.Millisecond},
+		{"chips", 59*time.Second + 995*time.Millisecond, 2 * time.Second},
+		{`"<3"`, time.Hour + time.Minute + time.Second + 5*time.Millisecond, 994 * time.Millisecond},
+		{"it's", 2*time.Hour - time.Millisecond, 500 * time.Millisecond},
+	}
+
+	sm := NewSubMaker()
+	for _, word := range words {
+		// Offsets are in 100-nanosecond ticks
+		err := sm.Feed(types.TTSChunk{
+			Type:     "WordBoundary",
+			Offset:   float64(word.start / 100),
+			Duration: float64(word.duration / 100),
+			Text:     word.text,
+		})
+		if err != nil {
+			t.Fatalf("Feed: %v", err)
+		}
+	}
+	if err := sm.MergeCues(2); err != nil {
+		t.Fatalf("MergeCues: %v", err)
+	}
+	return sm
+}
+
+func TestFormatsGolden(t *testing.T) {
+	tests := []struct {
+		file   string
+		format func(*SubMaker) string
+	}{
+		{"words.lrc", func(sm *SubMaker) string { return sm.GetLRC(false) }},
+		{"words.enhanced.lrc", func(sm *SubMaker) string { return sm.GetLRC(true) }},
+		{"words.ttml", func(sm *SubMaker) string { return sm.GetTTML("fr-CA") }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			got := tt.format(newGoldenSubMaker(t))
+
+			file := filepath.Join("testdata", tt.file)
+			if *update {
+				if err := os.WriteFile(file, []byte(got), 0o644); err != nil {
+					t.Fatalf("WriteFile: %v", err)
+				}
+			}
+			want, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatalf("ReadFile: %v", err)
+			}
+			if got != string(want) {
+				t.Errorf("subtitles differ from %s:\n%s\nwant:\n%s", file, got, want)
+			}
+		})
+	}
+}
+
+func TestFormatLRCTimestamp(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{0, "00:00.00"},
+		{1234 * time.Millisecond, "00:01.23"},
+		{1235 * time.Millisecond, "00:01.24"},
+		{59*time.Second + 995*time.Millisecond, "01:00.00"},
+		{time.Hour + time.Minute + time.Second + 5*time.Millisecond, "61:01.01"},
+		{2*time.Hour - time.Millisecond, "120:00.00"},
+	}
+	for _, tt := range tests {
+		if got := formatLRCTimestamp(tt.d); got != tt.want {
+			t.Errorf("formatLRCTimestamp(%s) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestGetTTMLLang(t *testing.T) {
+	tests := []struct {
+		lang string
+		want string
+	}{
+		{"", `xml:lang="en"`},
+		{"zh-CN-liaoning", `xml:lang="zh-CN-liaoning"`},
+	}
+	for _, tt := range tests {
+		got := NewSubMaker().GetTTML(tt.lang)
+		if !strings.Contains(got, tt.want) {
+			t.Errorf("GetTTML(%q) = %q, want it to contain %s", tt.lang, got, tt.want)
+		}
+	}
+}
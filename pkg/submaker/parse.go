@@ -0,0 +1,227 @@
+package submaker
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NewSubMakerFromCues creates a new SubMaker holding the given cues.
+func NewSubMakerFromCues(cues []Subtitle) *SubMaker {
+	sm := NewSubMaker()
+	sm.cues = append(sm.cues, cues...)
+	return sm
+}
+
+// Cues returns a copy of the cues held by the SubMaker.
+func (sm *SubMaker) Cues() []Subtitle {
+	cues := make([]Subtitle, len(sm.cues))
+	copy(cues, sm.cues)
+	return cues
+}
+
+// markupRe matches the formatting markup of cue content: SRT and WebVTT tags such
+// as <i>, <c.yellow>, <v Speaker> and <00:00:01.000>, and SSA override blocks such
+// as {\an8}.
+var markupRe = regexp.MustCompile(`</?[A-Za-z0-9][^<>]*>|{\\[^{}]*}`)
+
+// PlainText returns the content of the cue without its formatting markup, with the
+// WebVTT character references unescaped, as it should be spoken.
+func (s Subtitle) PlainText() string {
+	lines := strings.Split(markupRe.ReplaceAllString(s.Content, ""), "\n")
+	plain := lines[:0]
+	for _, line := range lines {
+		if line = strings.Join(strings.Fields(html.UnescapeString(line)), " "); line != "" {
+			plain = append(plain, line)
+		}
+	}
+	return strings.Join(plain, "\n")
+}
+
+// Parse parses SRT or WebVTT subtitles, detecting the format from the "WEBVTT" header.
+func Parse(r io.Reader) ([]Subtitle, error) {
+	blocks, err := readBlocks(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(blocks) > 0 && strings.HasPrefix(blocks[0][0], "WEBVTT") {
+		return parseVTTBlocks(blocks)
+	}
+	return parseSRTBlocks(blocks)
+}
+
+// ParseSRT parses SRT subtitles into cues.
+func ParseSRT(r io.Reader) ([]Subtitle, error) {
+	blocks, err := readBlocks(r)
+	if err != nil {
+		return nil, err
+	}
+	return parseSRTBlocks(blocks)
+}
+
+// ParseVTT parses WebVTT subtitles into cues. Cue settings, NOTE, STYLE and
+// REGION blocks are ignored.
+func ParseVTT(r io.Reader) ([]Subtitle, error) {
+	blocks, err := readBlocks(r)
+	if err != nil {
+		return nil, err
+	}
+	return parseVTTBlocks(blocks)
+}
+
+// readBlocks splits the input into blocks of non-empty lines separated by blank lines.
+func readBlocks(r io.Reader) ([][]string, error) {
+	var blocks [][]string
+	var current []string
+
+	scanner := bufio.NewScanner(r)
+	first := true
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if first {
+			// Strip the UTF-8 byte order mark
+			line = strings.TrimPrefix(line, "\ufeff")
+			first = false
+		}
+
+		if strings.TrimSpace(line) == "" {
+			if len(current) > 0 {
+				blocks = append(blocks, current)
+				current = nil
+			}
+			continue
+		}
+		current = append(current, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(current) > 0 {
+		blocks = append(blocks, current)
+	}
+
+	return blocks, nil
+}
+
+// parseSRTBlocks parses SRT blocks, each made of an index line, a timing line and the content.
+func parseSRTBlocks(blocks [][]string) ([]Subtitle, error) {
+	cues := []Subtitle{}
+	for _, block := range blocks {
+		// The index line is optional in practice, so look for the timing line
+		timing := 0
+		if !strings.Contains(block[0], "-->") {
+			timing = 1
+		}
+		if timing >= len(block) {
+			return nil, fmt.Errorf("invalid SRT cue, missing timing line: %q", block[0])
+		}
+
+		cue, err := parseCue(block[timing], block[timing+1:])
+		if err != nil {
+			return nil, err
+		}
+		cue.Index = len(cues) + 1
+		cues = append(cues, cue)
+	}
+	return cues, nil
+}
+
+// parseVTTBlocks parses WebVTT blocks, skipping the header and non-cue blocks.
+func parseVTTBlocks(blocks [][]string) ([]Subtitle, error) {
+	if len(blocks) == 0 || !strings.HasPrefix(blocks[0][0], "WEBVTT") {
+		return nil, fmt.Errorf("invalid WebVTT file, missing WEBVTT header")
+	}
+
+	cues := []Subtitle{}
+	for _, block := range blocks[1:] {
+		if strings.HasPrefix(block[0], "NOTE") || block[0] == "STYLE" || block[0] == "REGION" {
+			continue
+		}
+
+		// Skip the optional cue identifier
+		timing := 0
+		if !strings.Contains(block[0], "-->") {
+			timing = 1
+		}
+		if timing >= len(block) {
+			return nil, fmt.Errorf("invalid WebVTT cue, missing timing line: %q", block[0])
+		}
+
+		cue, err := parseCue(block[timing], block[timing+1:])
+		if err != nil {
+			return nil, err
+		}
+		cue.Index = len(cues) + 1
+		cues = append(cues, cue)
+	}
+	return cues, nil
+}
+
+// parseCue parses a "start --> end" timing line followed by the cue content lines.
+func parseCue(timing string, content []string) (Subtitle, error) {
+	parts := strings.SplitN(timing, "-->", 2)
+	if len(parts) != 2 {
+		return Subtitle{}, fmt.Errorf("invalid timing line: %q", timing)
+	}
+
+	start, err := parseTimestamp(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return Subtitle{}, err
+	}
+
+	// WebVTT cue settings may follow the end timestamp
+	endFields := strings.Fields(parts[1])
+	if len(endFields) == 0 {
+		return Subtitle{}, fmt.Errorf("invalid timing line: %q", timing)
+	}
+	end, err := parseTimestamp(endFields[0])
+	if err != nil {
+		return Subtitle{}, err
+	}
+
+	return Subtitle{
+		Start:   start,
+		End:     end,
+		Content: strings.Join(content, "\n"),
+	}, nil
+}
+
+// parseTimestamp parses "hh:mm:ss,mmm", "hh:mm:ss.mmm" or "mm:ss.mmm".
+func parseTimestamp(s string) (time.Duration, error) {
+	s = strings.Replace(s, ",", ".", 1)
+
+	clock, fraction, _ := strings.Cut(s, ".")
+	fields := strings.Split(clock, ":")
+	if len(fields) < 2 || len(fields) > 3 {
+		return 0, fmt.Errorf("invalid timestamp: %q", s)
+	}
+
+	var d time.Duration
+	for _, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid timestamp: %q", s)
+		}
+		d = d*60 + time.Duration(n)
+	}
+	d *= time.Second
+
+	if fraction != "" {
+		// Normalize the fraction to milliseconds
+		for len(fraction) < 3 {
+			fraction += "0"
+		}
+		ms, err := strconv.Atoi(fraction[:3])
+		if err != nil {
+			return 0, fmt.Errorf("invalid timestamp: %q", s)
+		}
+		d += time.Duration(ms) * time.Millisecond
+	}
+
+	return d, nil
+}
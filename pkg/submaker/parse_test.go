@@ -0,0 +1,171 @@
+package submaker
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ms returns a duration of n milliseconds.
+func ms(n int) time.Duration {
+	return time.Duration(n) * time.Millisecond
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []Subtitle
+	}{
+		{
+			name:  "srt",
+			input: "1\n00:00:01,000 --> 00:00:02,500\nHello\nworld\n\n2\n00:00:03,000 --> 00:00:04,000\nBye\n",
+			want: []Subtitle{
+				{Index: 1, Start: ms(1000), End: ms(2500), Content: "Hello\nworld"},
+				{Index: 2, Start: ms(3000), End: ms(4000), Content: "Bye"},
+			},
+		},
+		{
+			name:  "srt with BOM and CRLF",
+			input: "\ufeff1\r\n00:00:01,000 --> 00:00:02,000\r\nHello\r\n\r\n2\r\n00:00:02,000 --> 00:00:03,000\r\nworld\r\n",
+			want: []Subtitle{
+				{Index: 1, Start: ms(1000), End: ms(2000), Content: "Hello"},
+				{Index: 2, Start: ms(2000), End: ms(3000), Content: "world"},
+			},
+		},
+		{
+			name:  "srt without indices",
+			input: "00:00:01,000 --> 00:00:02,000\nHello\n\n\n00:00:02,000 --> 00:00:03,000\nworld\n",
+			want: []Subtitle{
+				{Index: 1, Start: ms(1000), End: ms(2000), Content: "Hello"},
+				{Index: 2, Start: ms(2000), End: ms(3000), Content: "world"},
+			},
+		},
+		{
+			name:  "srt renumbered",
+			input: "7\n00:00:01,000 --> 00:00:02,000\nHello\n\n9\n00:00:02,000 --> 00:00:03,000\nworld\n",
+			want: []Subtitle{
+				{Index: 1, Start: ms(1000), End: ms(2000), Content: "Hello"},
+				{Index: 2, Start: ms(2000), End: ms(3000), Content: "world"},
+			},
+		},
+		{
+			name: "vtt",
+			input: "WEBVTT - Example\n\n" +
+				"NOTE This is a comment\nspanning two lines\n\n" +
+				"STYLE\n::cue { color: yellow }\n\n" +
+				"intro\n00:01.000 --> 00:02.000 align:start position:10%\nHello\n\n" +
+				"00:00:02.000 --> 00:00:03.500 line:0\n<v Speaker>world</v>\n",
+			want: []Subtitle{
+				{Index: 1, Start: ms(1000), End: ms(2000), Content: "Hello"},
+				{Index: 2, Start: ms(2000), End: ms(3500), Content: "<v Speaker>world</v>"},
+			},
+		},
+		{
+			name:  "vtt with BOM and CRLF",
+			input: "\ufeffWEBVTT\r\n\r\nNOTE comment\r\n\r\n00:00:01.000 --> 00:00:02.000\r\nHello\r\n",
+			want: []Subtitle{
+				{Index: 1, Start: ms(1000), End: ms(2000), Content: "Hello"},
+			},
+		},
+		{
+			name:  "empty",
+			input: "",
+			want:  []Subtitle{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		parse func(string) error
+		input string
+	}{
+		{"srt missing timing", parseSRTString, "1\nHello\n"},
+		{"srt invalid timestamp", parseSRTString, "1\n00:00:xx,000 --> 00:00:02,000\nHello\n"},
+		{"srt missing end", parseSRTString, "1\n00:00:01,000 -->\nHello\n"},
+		{"vtt missing header", parseVTTString, "00:00:01.000 --> 00:00:02.000\nHello\n"},
+		{"vtt missing timing", parseVTTString, "WEBVTT\n\nintro\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.parse(tt.input); err == nil {
+				t.Errorf("no error for %q", tt.input)
+			}
+		})
+	}
+}
+
+func parseSRTString(s string) error {
+	_, err := ParseSRT(strings.NewReader(s))
+	return err
+}
+
+func parseVTTString(s string) error {
+	_, err := ParseVTT(strings.NewReader(s))
+	return err
+}
+
+func TestParseTimestamp(t *testing.T) {
+	tests := []struct {
+		input string
+		want  time.Duration
+	}{
+		{"00:00:01,000", ms(1000)},
+		{"01:02:03,456", time.Hour + 2*time.Minute + 3*time.Second + ms(456)},
+		{"00:00:01.5", ms(1500)},
+		{"02:03.040", 2*time.Minute + 3*time.Second + ms(40)},
+		{"00:00:07", 7 * time.Second},
+	}
+	for _, tt := range tests {
+		got, err := parseTimestamp(tt.input)
+		if err != nil {
+			t.Errorf("parseTimestamp(%q): %v", tt.input, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseTimestamp(%q) = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestPlainText(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{"Hello <i>World</i>", "Hello World"},
+		{"<b>Bold</b> and <u>under</u>", "Bold and under"},
+		{`<font color="#ffff00">Yellow</font>`, "Yellow"},
+		{"<c.yellow.bg_blue>Colored</c>", "Colored"},
+		{"<v Speaker>Hi there</v>", "Hi there"},
+		{"<v.loud Esme>Hi</v> <lang en>there</lang>", "Hi there"},
+		{"Karaoke <00:00:01.000>timed <00:00:01.500>words", "Karaoke timed words"},
+		{`{\an8}Top of the screen`, "Top of the screen"},
+		{`{\i1}Italic{\i0} text`, "Italic text"},
+		{"Fish &amp; chips &lt;3", "Fish & chips <3"},
+		{"1 < 2 and 3 > 2", "1 < 2 and 3 > 2"},
+		{"<i>Two</i>\n<i>lines</i>", "Two\nlines"},
+		{"<i></i>\nOnly line", "Only line"},
+		{"<i> </i>", ""},
+	}
+	for _, tt := range tests {
+		got := Subtitle{Content: tt.content}.PlainText()
+		if got != tt.want {
+			t.Errorf("PlainText(%q) = %q, want %q", tt.content, got, tt.want)
+		}
+	}
+}
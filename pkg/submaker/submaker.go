@@ -12,6 +12,9 @@ import (
 // SubMaker is used to generate subtitles from WordBoundary and SentenceBoundary messages.
 type SubMaker struct {
 	cues []Subtitle
+
+	// words keeps the boundary events as they were fed, unaffected by MergeCues.
+	words []Subtitle
 }
 
 // Subtitle represents a subtitle cue.
@@ -25,7 +28,8 @@ type Subtitle struct {
 // NewSubMaker creates a new SubMaker.
 func NewSubMaker() *SubMaker {
 	return &SubMaker{
-		cues: []Subtitle{},
+		cues:  []Subtitle{},
+		words: []Subtitle{},
 	}
 }
 
@@ -41,6 +45,7 @@ func (sm *SubMaker) Feed(msg types.TTSChunk) error {
 		End:     time.Duration((msg.Offset + msg.Duration) / 10) * time.Microsecond,
 		Content: msg.Text,
 	})
+	sm.words = append(sm.words, sm.cues[len(sm.cues)-1])
 
 	return nil
 }
@@ -79,6 +84,78 @@ func (sm *SubMaker) MergeCues(words int) error {
 	return nil
 }
 
+// Shift moves all cues by the given duration and renumbers them. Cues that end at or
+// before zero once shifted are dropped, and cues that start before zero are clamped
+// to start at zero.
+func (sm *SubMaker) Shift(offset time.Duration) {
+	shift := func(cues []Subtitle) []Subtitle {
+		shifted := cues[:0]
+		for _, cue := range cues {
+			cue.Start += offset
+			cue.End += offset
+			if offset < 0 && cue.End <= 0 {
+				continue
+			}
+			if cue.Start < 0 {
+				cue.Start = 0
+			}
+			shifted = append(shifted, cue)
+		}
+		return shifted
+	}
+
+	sm.cues = shift(sm.cues)
+	sm.words = shift(sm.words)
+	sm.Renumber()
+}
+
+// Scale multiplies all cue timings by the given factor. For example, audio sped up
+// by 1.25x needs a factor of 1/1.25 = 0.8.
+func (sm *SubMaker) Scale(factor float64) error {
+	if factor <= 0 {
+		return fmt.Errorf("invalid scale factor, expected > 0, got %f", factor)
+	}
+
+	scale := func(d time.Duration) time.Duration {
+		return time.Duration(float64(d) * factor)
+	}
+
+	for i := range sm.cues {
+		sm.cues[i].Start = scale(sm.cues[i].Start)
+		sm.cues[i].End = scale(sm.cues[i].End)
+	}
+	for i := range sm.words {
+		sm.words[i].Start = scale(sm.words[i].Start)
+		sm.words[i].End = scale(sm.words[i].End)
+	}
+
+	return nil
+}
+
+// Concat appends the cues of other, shifted by offset, and renumbers the cues.
+// The offset is usually the duration of the audio preceding other's audio.
+func (sm *SubMaker) Concat(other *SubMaker, offset time.Duration) {
+	for _, cue := range other.cues {
+		cue.Start += offset
+		cue.End += offset
+		sm.cues = append(sm.cues, cue)
+	}
+	for _, word := range other.words {
+		word.Start += offset
+		word.End += offset
+		sm.words = append(sm.words, word)
+	}
+
+	sm.Renumber()
+}
+
+// Renumber sets the Index of each cue to its position, starting at 1.
+func (sm *SubMaker) Renumber() {
+	for i := range sm.cues {
+		sm.cues[i].Index = i + 1
+	}
+}
+
 // GetSRT returns the SRT formatted subtitles from the SubMaker.
 func (sm *SubMaker) GetSRT() string {
 	var sb strings.Builder
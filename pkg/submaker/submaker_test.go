@@ -0,0 +1,134 @@
+package submaker
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/difyz9/edge-tts-go/pkg/types"
+)
+
+// newTestSubMaker returns a SubMaker fed with one word per second, each lasting 500ms.
+func newTestSubMaker(t *testing.T, words ...string) *SubMaker {
+	t.Helper()
+	sm := NewSubMaker()
+	for i, word := range words {
+		// Offsets are in 100-nanosecond ticks
+		err := sm.Feed(types.TTSChunk{
+			Type:     "WordBoundary",
+			Offset:   float64(time.Duration(i) * time.Second / 100),
+			Duration: float64(500 * time.Millisecond / 100),
+			Text:     word,
+		})
+		if err != nil {
+			t.Fatalf("Feed: %v", err)
+		}
+	}
+	return sm
+}
+
+func TestShift(t *testing.T) {
+	tests := []struct {
+		name   string
+		offset time.Duration
+		want   []Subtitle
+	}{
+		{
+			name:   "forward",
+			offset: 2 * time.Second,
+			want: []Subtitle{
+				{Index: 1, Start: ms(2000), End: ms(2500), Content: "one"},
+				{Index: 2, Start: ms(3000), End: ms(3500), Content: "two"},
+				{Index: 3, Start: ms(4000), End: ms(4500), Content: "three"},
+			},
+		},
+		{
+			name:   "backward",
+			offset: -time.Second,
+			want: []Subtitle{
+				{Index: 1, Start: 0, End: ms(500), Content: "two"},
+				{Index: 2, Start: ms(1000), End: ms(1500), Content: "three"},
+			},
+		},
+		{
+			name:   "clamped",
+			offset: -1250 * time.Millisecond,
+			want: []Subtitle{
+				{Index: 1, Start: 0, End: ms(250), Content: "two"},
+				{Index: 2, Start: ms(750), End: ms(1250), Content: "three"},
+			},
+		},
+		{
+			name:   "end at zero",
+			offset: -2500 * time.Millisecond,
+			want:   []Subtitle{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sm := newTestSubMaker(t, "one", "two", "three")
+			sm.Shift(tt.offset)
+			if got := sm.Cues(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Cues() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShiftSRT(t *testing.T) {
+	sm := newTestSubMaker(t, "one", "two")
+	sm.Shift(-time.Second)
+
+	want := "1\n00:00:00,000 --> 00:00:00,500\ntwo\n\n"
+	if got := sm.GetSRT(); got != want {
+		t.Errorf("GetSRT() = %q, want %q", got, want)
+	}
+}
+
+func TestScale(t *testing.T) {
+	sm := newTestSubMaker(t, "one", "two")
+	if err := sm.Scale(0.5); err != nil {
+		t.Fatalf("Scale: %v", err)
+	}
+
+	want := []Subtitle{
+		{Index: 1, Start: 0, End: ms(250), Content: "one"},
+		{Index: 2, Start: ms(500), End: ms(750), Content: "two"},
+	}
+	if got := sm.Cues(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Cues() = %+v, want %+v", got, want)
+	}
+
+	if err := sm.Scale(0); err == nil {
+		t.Errorf("Scale(0) returned no error")
+	}
+}
+
+func TestConcat(t *testing.T) {
+	sm := newTestSubMaker(t, "one")
+	sm.Concat(newTestSubMaker(t, "two", "three"), 10*time.Second)
+
+	want := []Subtitle{
+		{Index: 1, Start: 0, End: ms(500), Content: "one"},
+		{Index: 2, Start: ms(10000), End: ms(10500), Content: "two"},
+		{Index: 3, Start: ms(11000), End: ms(11500), Content: "three"},
+	}
+	if got := sm.Cues(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Cues() = %+v, want %+v", got, want)
+	}
+}
+
+func TestMergeCues(t *testing.T) {
+	sm := newTestSubMaker(t, "one", "two", "three")
+	if err := sm.MergeCues(2); err != nil {
+		t.Fatalf("MergeCues: %v", err)
+	}
+
+	want := []Subtitle{
+		{Index: 1, Start: 0, End: ms(1500), Content: "one two"},
+		{Index: 2, Start: ms(2000), End: ms(2500), Content: "three"},
+	}
+	if got := sm.Cues(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Cues() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,34 @@
+package submaker
+
+import (
+	"fmt"
+
+	"github.com/difyz9/edge-tts-go/pkg/types"
+)
+
+// Tracks keeps WordBoundary and SentenceBoundary messages in separate SubMakers,
+// for synthesis with the "WordAndSentenceBoundary" boundary.
+type Tracks struct {
+	Words     *SubMaker
+	Sentences *SubMaker
+}
+
+// NewTracks creates a new Tracks with empty word and sentence SubMakers.
+func NewTracks() *Tracks {
+	return &Tracks{
+		Words:     NewSubMaker(),
+		Sentences: NewSubMaker(),
+	}
+}
+
+// Feed feeds a WordBoundary or SentenceBoundary message to the matching track.
+func (t *Tracks) Feed(msg types.TTSChunk) error {
+	switch msg.Type {
+	case "WordBoundary":
+		return t.Words.Feed(msg)
+	case "SentenceBoundary":
+		return t.Sentences.Feed(msg)
+	default:
+		return fmt.Errorf("invalid message type, expected 'WordBoundary' or 'SentenceBoundary', got '%s'", msg.Type)
+	}
+}
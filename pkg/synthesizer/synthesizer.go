@@ -0,0 +1,212 @@
+// Package synthesizer defines a backend-agnostic interface for text-to-speech, with
+// an implementation backed by Communicate and a composite that fails over to a
+// secondary backend.
+package synthesizer
+
+import (
+	"context"
+	"crypto/tls"
+	stderrors "errors"
+	"log/slog"
+
+	"github.com/difyz9/edge-tts-go/pkg/communicate"
+	"github.com/difyz9/edge-tts-go/pkg/drm"
+	"github.com/difyz9/edge-tts-go/pkg/endpoint"
+	"github.com/difyz9/edge-tts-go/pkg/errors"
+	"github.com/difyz9/edge-tts-go/pkg/metrics"
+	"github.com/difyz9/edge-tts-go/pkg/ratelimit"
+	"github.com/difyz9/edge-tts-go/pkg/types"
+	"github.com/difyz9/edge-tts-go/pkg/voices"
+)
+
+// Request represents a synthesis request. Empty fields use the Communicate defaults.
+type Request struct {
+	Text     string
+	Voice    string
+	Rate     string
+	Volume   string
+	Pitch    string
+	Boundary string
+}
+
+// Stream is a stream of chunks produced by a Synthesizer.
+//
+//	for stream.Next() {
+//		chunk := stream.Chunk()
+//		...
+//	}
+//	if err := stream.Err(); err != nil {
+//		...
+//	}
+type Stream interface {
+	// Next advances to the next chunk. It returns false when the stream ends or fails.
+	Next() bool
+
+	// Chunk returns the current chunk.
+	Chunk() types.TTSChunk
+
+	// Err returns the error that ended the stream, if any.
+	Err() error
+
+	// Close stops the stream and releases its connection.
+	Close() error
+}
+
+// Synthesizer is a text-to-speech backend.
+type Synthesizer interface {
+	// Synthesize starts synthesizing the request.
+	Synthesize(ctx context.Context, req Request) (Stream, error)
+
+	// Voices lists the voices available from the backend.
+	Voices(ctx context.Context) ([]types.Voice, error)
+}
+
+// Communicate is a Synthesizer backed by Communicate and voices.ListVoicesWithOptions.
+type Communicate struct {
+	Endpoint       endpoint.Endpoint
+	Proxy          string
+	TLSConfig      *tls.Config
+	ConnectTimeout int
+	ReceiveTimeout int
+	Logger         *slog.Logger
+	Metrics        metrics.Collector
+	Limiter        *ratelimit.Limiter
+	TokenGenerator *drm.TokenGenerator
+}
+
+// Synthesize starts synthesizing the request with a new Communicate.
+func (s *Communicate) Synthesize(ctx context.Context, req Request) (Stream, error) {
+	comm, err := communicate.NewCommunicate(
+		req.Text,
+		req.Voice,
+		req.Rate,
+		req.Volume,
+		req.Pitch,
+		s.Proxy,
+		s.ConnectTimeout,
+		s.ReceiveTimeout,
+		req.Boundary,
+	)
+	if err != nil {
+		return nil, err
+	}
+	comm.SetEndpoint(s.Endpoint)
+	comm.SetTLSConfig(s.TLSConfig)
+	comm.SetLogger(s.Logger)
+	comm.SetMetrics(s.Metrics)
+	comm.SetLimiter(s.Limiter)
+	comm.SetTokenGenerator(s.TokenGenerator)
+
+	return comm.Chunks(ctx), nil
+}
+
+// Voices lists the voices available from the endpoint.
+func (s *Communicate) Voices(ctx context.Context) ([]types.Voice, error) {
+	return voices.ListVoicesWithOptions(ctx, voices.Options{
+		Proxy:          s.Proxy,
+		TLSConfig:      s.TLSConfig,
+		Endpoint:       s.Endpoint,
+		Logger:         s.Logger,
+		Metrics:        s.Metrics,
+		Limiter:        s.Limiter,
+		TokenGenerator: s.TokenGenerator,
+	})
+}
+
+// IsFailoverError reports whether err is a connection, timeout, clock skew or token
+// rejection error, or a retryable error such as a 5xx response, after which Failover
+// tries its secondary Synthesizer. Context errors are not failover errors.
+func IsFailoverError(err error) bool {
+	if stderrors.Is(err, context.Canceled) || stderrors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var requestErr *errors.RequestError
+	return errors.IsRetryable(err) || errors.IsWebSocketError(err) || stderrors.As(err, &requestErr) ||
+		errors.IsSkewAdjustmentError(err) || errors.IsTokenRejectedError(err)
+}
+
+// Failover is a Synthesizer that falls back to Secondary when Primary fails.
+//
+// Synthesize waits for the first chunk of Primary: if Primary fails before producing
+// any chunk with an error accepted by ShouldFailover, the request is sent to
+// Secondary instead. Once a chunk was produced, errors are returned as is, since
+// part of the audio was already delivered.
+type Failover struct {
+	Primary   Synthesizer
+	Secondary Synthesizer
+
+	// ShouldFailover reports whether an error of Primary triggers the failover.
+	// Defaults to IsFailoverError.
+	ShouldFailover func(error) bool
+}
+
+// NewFailover creates a new Failover.
+func NewFailover(primary, secondary Synthesizer) *Failover {
+	return &Failover{
+		Primary:        primary,
+		Secondary:      secondary,
+		ShouldFailover: IsFailoverError,
+	}
+}
+
+// shouldFailover reports whether err triggers the failover.
+func (f *Failover) shouldFailover(err error) bool {
+	if f.ShouldFailover == nil {
+		return IsFailoverError(err)
+	}
+	return f.ShouldFailover(err)
+}
+
+// Synthesize synthesizes the request with Primary, or with Secondary if Primary
+// fails before producing any chunk.
+func (f *Failover) Synthesize(ctx context.Context, req Request) (Stream, error) {
+	stream, err := f.Primary.Synthesize(ctx, req)
+	if err != nil {
+		if f.shouldFailover(err) {
+			return f.Secondary.Synthesize(ctx, req)
+		}
+		return nil, err
+	}
+
+	// Wait for the first chunk to know whether Primary works
+	if stream.Next() {
+		return &peekedStream{Stream: stream}, nil
+	}
+
+	err = stream.Err()
+	stream.Close()
+	if err != nil && f.shouldFailover(err) {
+		return f.Secondary.Synthesize(ctx, req)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &peekedStream{Stream: stream, ended: true}, nil
+}
+
+// Voices lists the voices of Primary, or of Secondary if Primary fails.
+func (f *Failover) Voices(ctx context.Context) ([]types.Voice, error) {
+	list, err := f.Primary.Voices(ctx)
+	if err != nil && f.shouldFailover(err) {
+		return f.Secondary.Voices(ctx)
+	}
+	return list, err
+}
+
+// peekedStream is a Stream whose first chunk was already read with Next.
+type peekedStream struct {
+	Stream
+	started bool
+	ended   bool
+}
+
+func (s *peekedStream) Next() bool {
+	if s.ended {
+		return false
+	}
+	if !s.started {
+		s.started = true
+		return true
+	}
+	return s.Stream.Next()
+}
@@ -0,0 +1,138 @@
+package synthesizer
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/difyz9/edge-tts-go/pkg/drm"
+	"github.com/difyz9/edge-tts-go/pkg/endpoint"
+	"github.com/difyz9/edge-tts-go/pkg/ttstest"
+)
+
+// newTestSynthesizer creates a Communicate synthesizer for e, with its own token
+// generator so that tests do not share a clock skew.
+func newTestSynthesizer(e endpoint.Endpoint) *Communicate {
+	return &Communicate{
+		Endpoint:       e,
+		ConnectTimeout: 5,
+		ReceiveTimeout: 5,
+		TokenGenerator: drm.NewTokenGenerator(nil),
+	}
+}
+
+// closedEndpoint returns the endpoint of a port nothing listens on.
+func closedEndpoint(t *testing.T) endpoint.Endpoint {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+	return endpoint.Endpoint{
+		WSSURL:       "ws://" + addr + "/",
+		VoiceListURL: "http://" + addr + "/",
+	}
+}
+
+// failoverTests are the failing primaries shared by the Synthesize and Voices tests.
+var failoverTests = []struct {
+	name string
+
+	// setup makes primary fail and returns its endpoint
+	setup func(t *testing.T, primary *ttstest.Server) endpoint.Endpoint
+}{
+	{
+		name: "connection refused",
+		setup: func(t *testing.T, _ *ttstest.Server) endpoint.Endpoint {
+			return closedEndpoint(t)
+		},
+	},
+	{
+		name: "service unavailable",
+		setup: func(_ *testing.T, s *ttstest.Server) endpoint.Endpoint {
+			s.AddFault(ttstest.Fault{Status: http.StatusServiceUnavailable})
+			return s.Endpoint()
+		},
+	},
+	{
+		name: "token rejected",
+		setup: func(_ *testing.T, s *ttstest.Server) endpoint.Endpoint {
+			s.Version = "0.0.0.0"
+			return s.Endpoint()
+		},
+	},
+}
+
+func TestFailoverSynthesize(t *testing.T) {
+	for _, tt := range failoverTests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary := ttstest.NewServer()
+			defer primary.Close()
+			secondary := ttstest.NewServer()
+			defer secondary.Close()
+
+			failover := NewFailover(newTestSynthesizer(tt.setup(t, primary)), newTestSynthesizer(secondary.Endpoint()))
+			stream, err := failover.Synthesize(context.Background(), Request{Text: "Hello world"})
+			if err != nil {
+				t.Fatalf("Synthesize: %v", err)
+			}
+			defer stream.Close()
+
+			var audio int
+			for stream.Next() {
+				if stream.Chunk().Type == "audio" {
+					audio++
+				}
+			}
+			if err := stream.Err(); err != nil {
+				t.Fatalf("stream: %v", err)
+			}
+			if audio != 2 {
+				t.Errorf("audio chunks = %d, want 2", audio)
+			}
+			if got := len(secondary.Requests()); got != 1 {
+				t.Errorf("secondary requests = %d, want 1", got)
+			}
+		})
+	}
+}
+
+func TestFailoverVoices(t *testing.T) {
+	for _, tt := range failoverTests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary := ttstest.NewServer()
+			defer primary.Close()
+			secondary := ttstest.NewServer()
+			defer secondary.Close()
+
+			// The secondary tells itself apart by its voices
+			secondary.Voices = ttstest.DefaultVoices[:1]
+
+			failover := NewFailover(newTestSynthesizer(tt.setup(t, primary)), newTestSynthesizer(secondary.Endpoint()))
+			voices, err := failover.Voices(context.Background())
+
+			if err != nil {
+				t.Fatalf("Voices: %v", err)
+			}
+			if len(voices) != 1 {
+				t.Errorf("voices = %d, want the 1 voice of the secondary", len(voices))
+			}
+		})
+	}
+}
+
+func TestIsFailoverErrorCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := newTestSynthesizer(closedEndpoint(t)).Voices(ctx)
+	if err == nil {
+		t.Fatal("Voices succeeded, want an error")
+	}
+	if IsFailoverError(err) {
+		t.Errorf("IsFailoverError(%v) = true, want false", err)
+	}
+}
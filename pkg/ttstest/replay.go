@@ -0,0 +1,101 @@
+package ttstest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/difyz9/edge-tts-go/pkg/endpoint"
+	"github.com/difyz9/edge-tts-go/pkg/record"
+	"github.com/difyz9/edge-tts-go/pkg/util"
+	"github.com/gorilla/websocket"
+)
+
+// ReplayServer serves a recorded session back to clients, for golden tests of
+// stream parsing and subtitle generation.
+//
+// The n-th connection made to the ReplayServer replays the n-th recorded connection:
+// frames the client sent are read and checked against the recorded path, and frames
+// the client received are written back in their recorded order. Sec-MS-GEC tokens
+// are not validated, so sessions can be replayed at any time.
+type ReplayServer struct {
+	server   *httptest.Server
+	upgrader websocket.Upgrader
+
+	mu          sync.Mutex
+	connections [][]record.Frame
+}
+
+// NewReplayServer starts a new ReplayServer for the session. The caller must call
+// Close when done.
+func NewReplayServer(session *record.Session) *ReplayServer {
+	s := &ReplayServer{
+		upgrader: websocket.Upgrader{
+			// The client sends the Origin of the Microsoft Edge extension
+			CheckOrigin: func(*http.Request) bool { return true },
+		},
+		connections: session.Connections(),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(synthesisPath, s.handleSynthesis)
+	s.server = httptest.NewServer(mux)
+
+	return s
+}
+
+// Close shuts down the ReplayServer.
+func (s *ReplayServer) Close() {
+	s.server.Close()
+}
+
+// Endpoint returns the endpoint of the ReplayServer, to be passed to Communicate.SetEndpoint.
+func (s *ReplayServer) Endpoint() endpoint.Endpoint {
+	return serverEndpoint(s.server)
+}
+
+// handleSynthesis replays the next recorded connection.
+func (s *ReplayServer) handleSynthesis(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	if len(s.connections) == 0 {
+		s.mu.Unlock()
+		http.Error(w, "no more recorded connections", http.StatusGone)
+		return
+	}
+	frames := s.connections[0]
+	s.connections = s.connections[1:]
+	s.mu.Unlock()
+
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	for _, f := range frames {
+		if f.Direction == record.Sent {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+
+			// Stop replaying when the client diverges from the recording
+			headers, _ := util.ProcessWebsocketMessage(data)
+			if headers["Path"] != f.Headers["Path"] {
+				return
+			}
+			continue
+		}
+
+		if err := conn.WriteMessage(f.MessageType(), f.Data()); err != nil {
+			return
+		}
+	}
+
+	// Wait for the client to close the connection
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
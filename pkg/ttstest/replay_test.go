@@ -0,0 +1,140 @@
+package ttstest_test
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/difyz9/edge-tts-go/pkg/communicate"
+	"github.com/difyz9/edge-tts-go/pkg/drm"
+	"github.com/difyz9/edge-tts-go/pkg/endpoint"
+	"github.com/difyz9/edge-tts-go/pkg/record"
+	"github.com/difyz9/edge-tts-go/pkg/submaker"
+	"github.com/difyz9/edge-tts-go/pkg/ttstest"
+	"github.com/difyz9/edge-tts-go/pkg/types"
+)
+
+var update = flag.Bool("update", false, "record the golden session and subtitles in testdata again")
+
+// synthesize streams text from e, recording the session to recorder if not nil,
+// and returns the chunks with the subtitles generated from them.
+func synthesize(t *testing.T, e endpoint.Endpoint, text string, recorder *record.Recorder) ([]types.TTSChunk, string) {
+	t.Helper()
+	comm, err := communicate.NewCommunicate(text, "", "", "", "", "", 5, 5)
+	if err != nil {
+		t.Fatalf("NewCommunicate: %v", err)
+	}
+	comm.SetEndpoint(e)
+	comm.SetTokenGenerator(drm.NewTokenGenerator(nil))
+	if recorder != nil {
+		comm.SetRecorder(recorder)
+	}
+
+	var chunks []types.TTSChunk
+	subs := submaker.NewSubMaker()
+	chunkChan, errChan := comm.Stream(context.Background())
+	for chunk := range chunkChan {
+		chunks = append(chunks, chunk)
+		if chunk.Type == "WordBoundary" || chunk.Type == "SentenceBoundary" {
+			if err := subs.Feed(chunk); err != nil {
+				t.Fatalf("Feed: %v", err)
+			}
+		}
+	}
+	if err := <-errChan; err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+	return chunks, subs.GetSRT()
+}
+
+func TestRecordReplayRoundTrip(t *testing.T) {
+	server := ttstest.NewServer()
+	defer server.Close()
+
+	// Longer than a WebSocket message, so that it is split in two requests and
+	// two connections are replayed. Long words keep the number of frames low.
+	var words []string
+	for i := 0; i < 80; i++ {
+		words = append(words, strings.Repeat(string(rune('a'+i%26)), 1000))
+	}
+	text := strings.Join(words, " ")
+
+	var buf bytes.Buffer
+	recorder := record.NewRecorder(&buf)
+	chunks, srt := synthesize(t, server.Endpoint(), text, recorder)
+	if err := recorder.Close(); err != nil {
+		t.Fatalf("Recorder: %v", err)
+	}
+	if got := len(server.Requests()); got < 2 {
+		t.Fatalf("requests = %d, want at least 2", got)
+	}
+
+	session, err := record.ReadSession(&buf)
+	if err != nil {
+		t.Fatalf("ReadSession: %v", err)
+	}
+	replay := ttstest.NewReplayServer(session)
+	defer replay.Close()
+
+	replayChunks, replaySRT := synthesize(t, replay.Endpoint(), text, nil)
+	if !reflect.DeepEqual(replayChunks, chunks) {
+		t.Errorf("replayed chunks differ: got %d chunks, want %d", len(replayChunks), len(chunks))
+	}
+	if replaySRT != srt {
+		t.Errorf("replayed SRT differs:\n%s\nwant:\n%s", replaySRT, srt)
+	}
+}
+
+func TestReplayGolden(t *testing.T) {
+	const text = "Golden tests replay a recorded session."
+	sessionFile := filepath.Join("testdata", "session.jsonl")
+	srtFile := filepath.Join("testdata", "session.srt")
+
+	if *update {
+		server := ttstest.NewServer()
+		defer server.Close()
+
+		recorder, err := record.Create(sessionFile)
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		_, srt := synthesize(t, server.Endpoint(), text, recorder)
+		if err := recorder.Close(); err != nil {
+			t.Fatalf("Recorder: %v", err)
+		}
+		if err := os.WriteFile(srtFile, []byte(srt), 0o644); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+
+	session, err := record.Load(sessionFile)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	want, err := os.ReadFile(srtFile)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+
+	replay := ttstest.NewReplayServer(session)
+	defer replay.Close()
+
+	chunks, srt := synthesize(t, replay.Endpoint(), text, nil)
+	var audio int
+	for _, chunk := range chunks {
+		if chunk.Type == "audio" {
+			audio++
+		}
+	}
+	if audio != len(strings.Fields(text)) {
+		t.Errorf("audio chunks = %d, want %d", audio, len(strings.Fields(text)))
+	}
+	if srt != string(want) {
+		t.Errorf("SRT differs from %s:\n%s\nwant:\n%s", srtFile, srt, want)
+	}
+}
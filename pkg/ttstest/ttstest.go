@@ -0,0 +1,446 @@
+// Package ttstest provides an in-process fake of the TTS service for tests and
+// offline development.
+//
+// The fake speaks the same protocol as speech.platform.bing.com: it validates the
+// Sec-MS-GEC token against its own clock, accepts speech.config and ssml messages,
+// and answers with turn.start, audio.metadata, audio and turn.end messages. It also
+// serves the voice list. Faults can be queued to script failures.
+package ttstest
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/difyz9/edge-tts-go/internal/constants"
+	"github.com/difyz9/edge-tts-go/internal/mp3"
+	"github.com/difyz9/edge-tts-go/pkg/drm"
+	"github.com/difyz9/edge-tts-go/pkg/endpoint"
+	"github.com/difyz9/edge-tts-go/pkg/types"
+	"github.com/difyz9/edge-tts-go/pkg/util"
+	"github.com/gorilla/websocket"
+)
+
+// Paths served by the fake, mirroring the real service.
+const (
+	synthesisPath = "/consumer/speech/synthesize/readaloud/edge/v1"
+	voiceListPath = "/consumer/speech/synthesize/readaloud/voices/list"
+)
+
+// WordDuration is the duration of speech the fake produces for each word.
+const WordDuration = 300 * time.Millisecond
+
+// DefaultVoices are the voices served by a new Server.
+var DefaultVoices = []types.Voice{
+	{
+		Name:           "Microsoft Server Speech Text to Speech Voice (en-US, EmmaMultilingualNeural)",
+		ShortName:      "en-US-EmmaMultilingualNeural",
+		Gender:         "Female",
+		Locale:         "en-US",
+		SuggestedCodec: "audio-24khz-48kbitrate-mono-mp3",
+		FriendlyName:   "Microsoft Emma Online (Natural) - English (United States)",
+		Status:         "GA",
+		VoiceTag: types.VoiceTag{
+			ContentCategories:  []string{"Conversation", "Copilot"},
+			VoicePersonalities: []string{"Cheerful", "Clear"},
+		},
+	},
+	{
+		Name:           "Microsoft Server Speech Text to Speech Voice (en-US, GuyNeural)",
+		ShortName:      "en-US-GuyNeural",
+		Gender:         "Male",
+		Locale:         "en-US",
+		SuggestedCodec: "audio-24khz-48kbitrate-mono-mp3",
+		FriendlyName:   "Microsoft Guy Online (Natural) - English (United States)",
+		Status:         "GA",
+		VoiceTag: types.VoiceTag{
+			ContentCategories:  []string{"News", "Novel"},
+			VoicePersonalities: []string{"Passion"},
+		},
+	},
+}
+
+// Fault describes a failure injected into the next connection or voice list request.
+// Faults are consumed in the order they were added.
+type Fault struct {
+	// Status rejects the request with this HTTP status code, if non-zero.
+	Status int
+
+	// Header is sent with a rejected request, e.g. Retry-After.
+	Header http.Header
+
+	// CloseAfter closes the connection after this many frames of the turn were sent.
+	// Zero means the turn is sent completely.
+	CloseAfter int
+
+	// CloseCode sends a close frame with this code and CloseReason after CloseAfter
+	// frames, if non-zero, instead of closing the connection abruptly.
+	CloseCode   int
+	CloseReason string
+
+	// Stall stops sending frames after CloseAfter frames instead of closing the
+	// connection, leaving it open until the client gives up.
+	Stall bool
+
+	// NoAudio omits the audio frames of the turn.
+	NoAudio bool
+
+	// UnknownPath sends a text message with an unknown path before the turn.
+	UnknownPath bool
+}
+
+// Request is a synthesis request received by the Server.
+type Request struct {
+	Header http.Header
+	Query  map[string]string
+	Config string
+	SSML   string
+}
+
+// Server is an in-process fake of the TTS service.
+type Server struct {
+	// Voices are the voices served by the voice list. Defaults to DefaultVoices.
+	Voices []types.Voice
+
+	// Version, if set, is the only Sec-MS-GEC-Version accepted by the Server.
+	Version string
+
+	// BatchMetadata sends the metadata of a turn in a single audio.metadata message
+	// before the audio, like the service sometimes does, instead of one message per
+	// word. A Viseme entry follows each word when visemes are enabled, and a
+	// SessionEnd entry follows the first word.
+	BatchMetadata bool
+
+	// AudioMessageSize, if positive, splits the audio of each word into messages of
+	// this many bytes, which cut MP3 frames like the service does.
+	AudioMessageSize int
+
+	server   *httptest.Server
+	upgrader websocket.Upgrader
+
+	mu        sync.Mutex
+	clockSkew time.Duration
+	faults    []Fault
+	requests  []Request
+}
+
+// NewServer starts a new Server. The caller must call Close when done.
+func NewServer() *Server {
+	s := &Server{
+		Voices: DefaultVoices,
+		upgrader: websocket.Upgrader{
+			// The client sends the Origin of the Microsoft Edge extension
+			CheckOrigin: func(*http.Request) bool { return true },
+		},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(synthesisPath, s.handleSynthesis)
+	mux.HandleFunc(voiceListPath, s.handleVoiceList)
+	s.server = httptest.NewServer(mux)
+
+	return s
+}
+
+// Close shuts down the Server.
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// Endpoint returns the endpoint of the Server, to be passed to Communicate.SetEndpoint
+// or in voices.Options.
+func (s *Server) Endpoint() endpoint.Endpoint {
+	return serverEndpoint(s.server)
+}
+
+// serverEndpoint returns the endpoint of a fake served by server.
+func serverEndpoint(server *httptest.Server) endpoint.Endpoint {
+	return endpoint.Endpoint{
+		WSSURL:       "ws" + strings.TrimPrefix(server.URL, "http") + synthesisPath,
+		VoiceListURL: server.URL + voiceListPath,
+	}
+}
+
+// SetClockSkew sets how far the Server clock is ahead of the client clock. Requests
+// with a Sec-MS-GEC token not matching the Server clock are rejected with a 403 and
+// the Server's Date header, like the real service.
+func (s *Server) SetClockSkew(skew time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clockSkew = skew
+}
+
+// AddFault queues a fault for the next connection or voice list request.
+func (s *Server) AddFault(f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, f)
+}
+
+// Requests returns the synthesis requests received so far.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	requests := make([]Request, len(s.requests))
+	copy(requests, s.requests)
+	return requests
+}
+
+// nextFault pops the next queued fault.
+func (s *Server) nextFault() Fault {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.faults) == 0 {
+		return Fault{}
+	}
+	f := s.faults[0]
+	s.faults = s.faults[1:]
+	return f
+}
+
+// now returns the Server clock.
+func (s *Server) now() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return time.Now().Add(s.clockSkew)
+}
+
+// reject writes an error response if the fault or the DRM parameters require it.
+func (s *Server) reject(w http.ResponseWriter, r *http.Request, f Fault) bool {
+	now := s.now()
+	w.Header().Set("Date", now.UTC().Format(http.TimeFormat))
+
+	status := f.Status
+	if status == 0 {
+		query := r.URL.Query()
+		token := query.Get("TrustedClientToken") + query.Get("trustedclienttoken")
+		validToken := token == constants.TrustedClientToken &&
+			query.Get("Sec-MS-GEC") == drm.GenerateSecMSGECAt(float64(now.Unix()), token)
+		validVersion := s.Version == "" || query.Get("Sec-MS-GEC-Version") == s.Version
+		if !validToken || !validVersion {
+			status = http.StatusForbidden
+		}
+	}
+	if status == 0 {
+		return false
+	}
+
+	for k, values := range f.Header {
+		for _, v := range values {
+			w.Header().Add(k, v)
+		}
+	}
+	w.WriteHeader(status)
+	return true
+}
+
+// handleVoiceList serves the voice list.
+func (s *Server) handleVoiceList(w http.ResponseWriter, r *http.Request) {
+	if s.reject(w, r, s.nextFault()) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(s.Voices)
+}
+
+// handleSynthesis serves a synthesis WebSocket connection.
+func (s *Server) handleSynthesis(w http.ResponseWriter, r *http.Request) {
+	f := s.nextFault()
+	if s.reject(w, r, f) {
+		return
+	}
+
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	req := Request{Header: r.Header.Clone(), Query: map[string]string{}}
+	for k := range r.URL.Query() {
+		req.Query[k] = r.URL.Query().Get(k)
+	}
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		headers, body := util.ProcessWebsocketMessage(data)
+		switch headers["Path"] {
+		case "speech.config":
+			req.Config = string(body)
+		case "ssml":
+			req.SSML = string(body)
+
+			s.mu.Lock()
+			s.requests = append(s.requests, req)
+			s.mu.Unlock()
+
+			frames := s.turn(headers["X-RequestId"], req.Config, req.SSML, f)
+			for i, frame := range frames {
+				if (f.CloseAfter > 0 || f.CloseCode != 0 || f.Stall) && i == f.CloseAfter {
+					if f.CloseCode != 0 {
+						msg := websocket.FormatCloseMessage(f.CloseCode, f.CloseReason)
+						conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second))
+					}
+					if f.Stall {
+						// Wait for the client to give up
+						for {
+							if _, _, err := conn.ReadMessage(); err != nil {
+								return
+							}
+						}
+					}
+					return
+				}
+				if err := conn.WriteMessage(frame.messageType, frame.data); err != nil {
+					return
+				}
+			}
+		}
+	}
+}
+
+// frame is a single WebSocket message sent by the Server.
+type frame struct {
+	messageType int
+	data        []byte
+}
+
+// textFrame builds a text message with the given path and body.
+func textFrame(requestID, path, contentType, body string) frame {
+	return frame{websocket.TextMessage, []byte(fmt.Sprintf(
+		"X-RequestId:%s\r\nContent-Type:%s\r\nPath:%s\r\n\r\n%s",
+		requestID, contentType, path, body))}
+}
+
+// audioFrame builds a binary audio message, prefixed by the 2-byte header length.
+func audioFrame(requestID string, audio []byte) frame {
+	header := fmt.Sprintf("X-RequestId:%s\r\nContent-Type:audio/mpeg\r\nPath:audio\r\n", requestID)
+	data := make([]byte, 2, 2+len(header)+len(audio))
+	binary.BigEndian.PutUint16(data, uint16(len(header)))
+	data = append(data, header...)
+	data = append(data, audio...)
+	return frame{websocket.BinaryMessage, data}
+}
+
+// ssmlTextRe matches the text inside the prosody element.
+var ssmlTextRe = regexp.MustCompile(`(?s)<prosody[^>]*>(.*)</prosody>`)
+
+// tagRe matches SSML elements inside the text.
+var tagRe = regexp.MustCompile(`<[^>]*>`)
+
+// turn builds the frames answering a single SSML request: every word is spoken for
+// WordDuration, with one audio.metadata and one audio message per word, or a single
+// audio.metadata message for the whole turn with BatchMetadata.
+func (s *Server) turn(requestID, config, ssml string, f Fault) []frame {
+	batch := s.BatchMetadata
+	text := ""
+	if m := ssmlTextRe.FindStringSubmatch(ssml); m != nil {
+		text = html.UnescapeString(tagRe.ReplaceAllString(m[1], " "))
+	}
+	words := strings.Fields(text)
+
+	wordBoundary := strings.Contains(config, `"wordBoundaryEnabled":"true"`)
+	sentenceBoundary := strings.Contains(config, `"sentenceBoundaryEnabled":"true"`)
+	visemes := strings.Contains(config, `"visemeEnabled":"true"`)
+
+	frames := []frame{}
+	if f.UnknownPath {
+		frames = append(frames, textFrame(requestID, "unknown.path", "application/json", "{}"))
+	}
+	frames = append(frames,
+		textFrame(requestID, "turn.start", "application/json; charset=utf-8",
+			`{"context":{"serviceTag":"ttstest"}}`),
+		textFrame(requestID, "response", "application/json; charset=utf-8",
+			`{"context":{"serviceTag":"ttstest"},"audio":{"type":"inline","streamId":"ttstest"}}`))
+
+	// Offsets are in 100-nanosecond ticks
+	ticks := func(d time.Duration) int64 { return int64(d / 100) }
+
+	var entries []map[string]interface{}
+	if sentenceBoundary && len(words) > 0 {
+		entries = append(entries, boundaryEntry("SentenceBoundary", 0,
+			ticks(WordDuration*time.Duration(len(words))), strings.Join(words, " ")))
+	}
+	if batch {
+		for i, word := range words {
+			offset := ticks(WordDuration * time.Duration(i))
+			if wordBoundary {
+				entries = append(entries, boundaryEntry("WordBoundary", offset, ticks(WordDuration), word))
+			}
+			if visemes {
+				entries = append(entries, visemeEntry(offset, i+1))
+			}
+			if i == 0 {
+				entries = append(entries, map[string]interface{}{"Type": "SessionEnd", "Data": map[string]interface{}{}})
+			}
+		}
+	}
+	if len(entries) > 0 {
+		frames = append(frames, textFrame(requestID, "audio.metadata", "application/json", metadata(entries...)))
+	}
+
+	for i, word := range words {
+		if wordBoundary && !batch {
+			frames = append(frames, textFrame(requestID, "audio.metadata", "application/json",
+				metadata(boundaryEntry("WordBoundary", ticks(WordDuration*time.Duration(i)), ticks(WordDuration), word))))
+		}
+		if !f.NoAudio {
+			audio := mp3.Silence(WordDuration)
+			for s.AudioMessageSize > 0 && len(audio) > s.AudioMessageSize {
+				frames = append(frames, audioFrame(requestID, audio[:s.AudioMessageSize]))
+				audio = audio[s.AudioMessageSize:]
+			}
+			frames = append(frames, audioFrame(requestID, audio))
+		}
+	}
+
+	frames = append(frames, textFrame(requestID, "turn.end", "application/json",
+		`{"context":{"serviceTag":"ttstest"}}`))
+
+	return frames
+}
+
+// metadata builds the body of an audio.metadata message with the given entries.
+func metadata(entries ...map[string]interface{}) string {
+	body, _ := json.Marshal(map[string]interface{}{"Metadata": entries})
+	return string(body)
+}
+
+// boundaryEntry builds a WordBoundary or SentenceBoundary metadata entry.
+func boundaryEntry(metaType string, offset, duration int64, text string) map[string]interface{} {
+	return map[string]interface{}{
+		"Type": metaType,
+		"Data": map[string]interface{}{
+			"Offset":   offset,
+			"Duration": duration,
+			"text": map[string]interface{}{
+				"Text":         text,
+				"Length":       len(text),
+				"BoundaryType": metaType,
+			},
+		},
+	}
+}
+
+// visemeEntry builds a Viseme metadata entry.
+func visemeEntry(offset int64, visemeID int) map[string]interface{} {
+	return map[string]interface{}{
+		"Type": "Viseme",
+		"Data": map[string]interface{}{
+			"Offset":   offset,
+			"VisemeId": visemeID,
+		},
+	}
+}
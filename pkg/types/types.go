@@ -3,20 +3,23 @@ package types
 
 // TTSConfig represents the internal TTS configuration for edge-tts-go's Communicate struct.
 type TTSConfig struct {
-	Voice    string
-	Rate     string
-	Volume   string
-	Pitch    string
-	Boundary string // "WordBoundary" or "SentenceBoundary"
+	Voice     string
+	Rate      string
+	Volume    string
+	Pitch     string
+	Boundary  string // "WordBoundary", "SentenceBoundary" or "WordAndSentenceBoundary"
+	Bookmarks bool   // report Bookmark events for <bookmark mark="..."/> elements
+	Visemes   bool   // report Viseme events
 }
 
 // TTSChunk represents a chunk of data from the TTS service.
 type TTSChunk struct {
-	Type     string // "audio", "WordBoundary", or "SentenceBoundary"
-	Data     []byte // only for audio
+	Type     string  // "audio", "WordBoundary", "SentenceBoundary", "Bookmark" or "Viseme"
+	Data     []byte  // only for audio
 	Duration float64 // only for WordBoundary and SentenceBoundary
-	Offset   float64 // only for WordBoundary and SentenceBoundary
-	Text     string  // only for WordBoundary and SentenceBoundary
+	Offset   float64 // only for WordBoundary, SentenceBoundary, Bookmark and Viseme
+	Text     string  // only for WordBoundary and SentenceBoundary, or the mark name for Bookmark
+	VisemeID int     // only for Viseme
 }
 
 // VoiceTag represents the voice tag data.
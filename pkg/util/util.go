@@ -4,10 +4,12 @@ package util
 import (
 	"bytes"
 	"fmt"
+	"html"
 	"regexp"
 	"strings"
 	"time"
 	"unicode"
+	"unicode/utf8"
 
 	"github.com/difyz9/edge-tts-go/pkg/types"
 	"github.com/google/uuid"
@@ -114,6 +116,20 @@ func SplitTextByByteLength(text string, byteLength int) [][]byte {
 			}
 		}
 
+		// Verify we are not splitting inside a <bookmark/> element. A bookmark at
+		// the start of the text is kept whole by splitting after it instead.
+		if tagIndex := bytes.LastIndex(textBytes[:splitAt], []byte("<")); tagIndex != -1 &&
+			!bytes.Contains(textBytes[tagIndex:splitAt], []byte(">")) {
+			if tagIndex > 0 {
+				splitAt = tagIndex
+			} else if closeIndex := bytes.Index(textBytes, []byte(">")); closeIndex != -1 {
+				if closeIndex+1 > byteLength {
+					panic("Maximum byte length is smaller than a bookmark element")
+				}
+				splitAt = closeIndex + 1
+			}
+		}
+
 		// Append the string to the list
 		newText := bytes.TrimSpace(textBytes[:splitAt])
 		if len(newText) > 0 {
@@ -220,8 +236,9 @@ func ValidateTTSConfig(config *types.TTSConfig) error {
 	}
 
 	// Validate the boundary parameter
-	if config.Boundary != "" && config.Boundary != "WordBoundary" && config.Boundary != "SentenceBoundary" {
-		return fmt.Errorf("invalid boundary '%s', expected 'WordBoundary' or 'SentenceBoundary'", config.Boundary)
+	if config.Boundary != "" && config.Boundary != "WordBoundary" && config.Boundary != "SentenceBoundary" &&
+		config.Boundary != "WordAndSentenceBoundary" {
+		return fmt.Errorf("invalid boundary '%s', expected 'WordBoundary', 'SentenceBoundary' or 'WordAndSentenceBoundary'", config.Boundary)
 	}
 	
 	// Set default boundary if not provided
@@ -254,6 +271,38 @@ func EscapeXML(s string) string {
 	return result.String()
 }
 
+// bookmarkRe matches an escaped <bookmark mark="..."/> element.
+var bookmarkRe = regexp.MustCompile(`&lt;bookmark\s+mark=(?:&quot;|&apos;)([^&]*)(?:&quot;|&apos;)\s*/&gt;`)
+
+// RestoreBookmarks turns the <bookmark mark="..."/> elements escaped by EscapeXML
+// back into SSML, so that bookmarks in the input text are reported by the service.
+func RestoreBookmarks(escapedText string) string {
+	return bookmarkRe.ReplaceAllString(escapedText, "<bookmark mark='$1'/>")
+}
+
+// ssmlBookmarkRe matches a <bookmark mark='...'/> element restored by RestoreBookmarks.
+var ssmlBookmarkRe = regexp.MustCompile(`<bookmark mark='[^']*'/>`)
+
+// ValidateBookmarks returns an error if a bookmark element of the text returned by
+// RestoreBookmarks is longer than byteLength, as SplitTextByByteLength cannot keep
+// it whole.
+func ValidateBookmarks(text string, byteLength int) error {
+	for _, element := range ssmlBookmarkRe.FindAllString(text, -1) {
+		if len(element) > byteLength {
+			return fmt.Errorf("bookmark %s is longer than the maximum of %d bytes", element, byteLength)
+		}
+	}
+	return nil
+}
+
+// UnescapedLength returns the number of characters of a partial text escaped by
+// EscapeXML, as written in the input text: an entity counts as one character and
+// the elements restored by RestoreBookmarks are not counted.
+func UnescapedLength(escapedText []byte) int {
+	text := ssmlBookmarkRe.ReplaceAll(escapedText, nil)
+	return utf8.RuneCountInString(html.UnescapeString(string(text)))
+}
+
 // IsSpace returns true if the rune is a space.
 func IsSpace(r rune) bool {
 	return unicode.IsSpace(r)
@@ -0,0 +1,102 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitTextByByteLength(t *testing.T) {
+	tests := []struct {
+		name       string
+		text       string
+		byteLength int
+		want       []string
+	}{
+		{
+			name:       "short",
+			text:       "hello world",
+			byteLength: 20,
+			want:       []string{"hello world"},
+		},
+		{
+			name:       "at spaces",
+			text:       "hello world again",
+			byteLength: 12,
+			want:       []string{"hello world", "again"},
+		},
+		{
+			name:       "entity",
+			text:       "fish &amp; chips",
+			byteLength: 8,
+			want:       []string{"fish", "&amp;", "chips"},
+		},
+		{
+			name:       "bookmark",
+			text:       "hello <bookmark mark='a b'/> world",
+			byteLength: 22,
+			want:       []string{"hello", "<bookmark mark='a b'/>", "world"},
+		},
+		{
+			name:       "bookmark at start",
+			text:       "<bookmark mark='a b'/>hello world",
+			byteLength: 22,
+			want:       []string{"<bookmark mark='a b'/>", "hello world"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, part := range SplitTextByByteLength(tt.text, tt.byteLength) {
+				got = append(got, string(part))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SplitTextByByteLength(%q, %d) = %q, want %q", tt.text, tt.byteLength, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitTextByByteLengthLongBookmark(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("no panic for a bookmark longer than the byte length")
+		}
+	}()
+	SplitTextByByteLength("<bookmark mark='a b c d e'/> hello world again", 20)
+}
+
+func TestValidateBookmarks(t *testing.T) {
+	tests := []struct {
+		text       string
+		byteLength int
+		wantErr    bool
+	}{
+		{"hello <bookmark mark='a'/> world", 20, false},
+		{"hello <bookmark mark='a b c d e'/> world", 20, true},
+		{"hello &lt;bookmark mark=&apos;a b c d e&apos;/&gt; world", 20, false},
+	}
+	for _, tt := range tests {
+		err := ValidateBookmarks(tt.text, tt.byteLength)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ValidateBookmarks(%q, %d) = %v, want error %v", tt.text, tt.byteLength, err, tt.wantErr)
+		}
+	}
+}
+
+func TestUnescapedLength(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"hello", 5},
+		{"fish &amp; chips", 12},
+		{"&lt;3 &quot;quoted&quot; it&apos;s", 16},
+		{"héllo wörld", 11},
+		{"hello <bookmark mark='a'/> world", 12},
+	}
+	for _, tt := range tests {
+		if got := UnescapedLength([]byte(tt.text)); got != tt.want {
+			t.Errorf("UnescapedLength(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
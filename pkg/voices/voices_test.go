@@ -0,0 +1,114 @@
+package voices
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/difyz9/edge-tts-go/pkg/drm"
+	"github.com/difyz9/edge-tts-go/pkg/errors"
+	"github.com/difyz9/edge-tts-go/pkg/ttstest"
+	"github.com/difyz9/edge-tts-go/pkg/types"
+)
+
+func TestListVoicesWithOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   func(*ttstest.Server)
+		want    []types.Voice
+		wantErr error
+	}{
+		{
+			name:  "voice list",
+			setup: func(*ttstest.Server) {},
+			want:  ttstest.DefaultVoices,
+		},
+		{
+			name: "trimmed tags",
+			setup: func(s *ttstest.Server) {
+				s.Voices = []types.Voice{{
+					ShortName: "en-GB-SoniaNeural",
+					VoiceTag: types.VoiceTag{
+						ContentCategories:  []string{" General "},
+						VoicePersonalities: []string{"Friendly ", " Positive"},
+					},
+				}}
+			},
+			want: []types.Voice{{
+				ShortName: "en-GB-SoniaNeural",
+				VoiceTag: types.VoiceTag{
+					ContentCategories:  []string{"General"},
+					VoicePersonalities: []string{"Friendly", "Positive"},
+				},
+			}},
+		},
+		{
+			name:  "clock skew retry",
+			setup: func(s *ttstest.Server) { s.SetClockSkew(-time.Hour) },
+			want:  ttstest.DefaultVoices,
+		},
+		{
+			name:    "outdated version",
+			setup:   func(s *ttstest.Server) { s.Version = "0.0.0.0" },
+			wantErr: errors.ErrTokenRejected,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := ttstest.NewServer()
+			defer server.Close()
+			tt.setup(server)
+
+			voices, err := ListVoicesWithOptions(context.Background(), Options{
+				Endpoint:       server.Endpoint(),
+				TokenGenerator: drm.NewTokenGenerator(nil),
+			})
+			if tt.wantErr != nil {
+				if !stderrors.Is(err, tt.wantErr) {
+					t.Fatalf("error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ListVoicesWithOptions: %v", err)
+			}
+
+			if len(voices) != len(tt.want) {
+				t.Fatalf("voices = %d, want %d", len(voices), len(tt.want))
+			}
+			for i, voice := range voices {
+				want := tt.want[i]
+				if voice.ShortName != want.ShortName || voice.Locale != want.Locale || voice.Gender != want.Gender {
+					t.Errorf("voice %d = %+v, want %+v", i, voice, want)
+				}
+				if !slices.Equal(voice.VoiceTag.ContentCategories, want.VoiceTag.ContentCategories) ||
+					!slices.Equal(voice.VoiceTag.VoicePersonalities, want.VoiceTag.VoicePersonalities) {
+					t.Errorf("voice %d tags = %+v, want %+v", i, voice.VoiceTag, want.VoiceTag)
+				}
+			}
+		})
+	}
+}
+
+func TestListVoicesThrottled(t *testing.T) {
+	server := ttstest.NewServer()
+	defer server.Close()
+	server.AddFault(ttstest.Fault{
+		Status: http.StatusTooManyRequests,
+		Header: http.Header{"Retry-After": {"30"}},
+	})
+
+	_, err := ListVoicesWithOptions(context.Background(), Options{
+		Endpoint:       server.Endpoint(),
+		TokenGenerator: drm.NewTokenGenerator(nil),
+	})
+	if !errors.IsThrottled(err) {
+		t.Errorf("IsThrottled(%v) = false, want true", err)
+	}
+	if d, ok := errors.RetryAfter(err); !ok || d != 30*time.Second {
+		t.Errorf("RetryAfter(%v) = %s, %v, want 30s, true", err, d, ok)
+	}
+}